@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	issuer, err := NewIssuer(bytes.Repeat([]byte{3}, MinSecretSize), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_750_000_000, 0)
+	valid, _, err := issuer.Issue("root", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, ".")
+
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	// signed builds a token with the issuer's signature over any header and
+	// payload.
+	signed := func(header, payload string) string {
+		s := encode(header) + "." + encode(payload)
+		return s + "." + issuer.sign(s)
+	}
+	hs256 := `{"alg":"HS256","typ":"JWT"}`
+
+	tests := []struct {
+		name  string
+		token string
+		at    time.Time
+		want  error
+	}{
+		{"valid", valid, now, nil},
+		{"valid until expiry", valid, now.Add(time.Hour - time.Second), nil},
+		{"expired", valid, now.Add(time.Hour), ErrExpiredToken},
+		{"not yet valid", valid, now.Add(-time.Minute), ErrInvalidToken},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), now, ErrInvalidToken},
+		{"tampered payload", parts[0] + "." + encode(`{"sub":"admin","iat":1750000000,"exp":1750003600}`) + "." + parts[2], now, ErrInvalidToken},
+		{"other secret", func() string {
+			other, _ := NewIssuer(bytes.Repeat([]byte{4}, MinSecretSize), time.Hour)
+			token, _, _ := other.Issue("root", now)
+			return token
+		}(), now, ErrInvalidToken},
+		{"alg none", encode(`{"alg":"none","typ":"JWT"}`) + "." + parts[1] + ".", now, ErrInvalidToken},
+		{"alg HS512", signed(`{"alg":"HS512","typ":"JWT"}`, `{"sub":"root","iat":1750000000,"exp":1750003600}`), now, ErrInvalidToken},
+		{"no subject", signed(hs256, `{"iat":1750000000,"exp":1750003600}`), now, ErrInvalidToken},
+		{"two segments", parts[0] + "." + parts[1], now, ErrInvalidToken},
+		{"four segments", valid + ".x", now, ErrInvalidToken},
+		{"empty", "", now, ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := issuer.Verify(tt.token, tt.at)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify = %v, want %v", err, tt.want)
+			}
+			if err == nil && claims.Subject != "root" {
+				t.Errorf("subject = %q, want root", claims.Subject)
+			}
+		})
+	}
+}
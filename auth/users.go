@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const passwordIterations = 600000
+
+// dummyHash is checked for unknown users so they take as long to reject as
+// a wrong password.
+var dummyHash = fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+	strings.Repeat("A", 22), strings.Repeat("A", 43))
+
+type User struct {
+	Name         string `json:"name"`
+	PasswordHash string `json:"passwordHash"`
+	// Sellers are the vault seller IDs the user may report on.
+	Sellers []string `json:"sellers"`
+	// Admin may see every seller and manage the vault.
+	Admin bool `json:"admin"`
+}
+
+func (u User) CanAccess(sellerID string) bool {
+	return u.Admin || slices.Contains(u.Sellers, sellerID)
+}
+
+// Users is the users file. It is reread when it changes on disk, so users
+// edited with the CLI take effect without restarting the server.
+type Users struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	users   map[string]User
+}
+
+func OpenUsers(path string) (*Users, error) {
+	u := &Users{path: path, users: map[string]User{}}
+	if err := u.reload(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *Users) Get(name string) (User, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.reload(); err != nil {
+		slog.Warn("Cannot reload users", "file", u.path, "error", err)
+	}
+	user, ok := u.users[name]
+	return user, ok
+}
+
+// Authenticate returns the user when password matches.
+func (u *Users) Authenticate(name, password string) (User, bool) {
+	user, ok := u.Get(name)
+	if !ok {
+		// Spend the same time as a real check so names can't be probed.
+		checkPassword(password, dummyHash)
+		return User{}, false
+	}
+	return user, checkPassword(password, user.PasswordHash)
+}
+
+// Put adds or replaces a user and saves the file.
+func (u *Users) Put(user User) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.reload(); err != nil {
+		return err
+	}
+	u.users[user.Name] = user
+	return u.save()
+}
+
+func (u *Users) Delete(name string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if err := u.reload(); err != nil {
+		return err
+	}
+	if _, ok := u.users[name]; !ok {
+		return fmt.Errorf("user %q not found", name)
+	}
+	delete(u.users, name)
+	return u.save()
+}
+
+// reload rereads the file if it changed since the last read. Callers hold mu.
+func (u *Users) reload() error {
+	info, err := os.Stat(u.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(u.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(u.path)
+	if err != nil {
+		return err
+	}
+	var list []User
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", u.path, err)
+	}
+	users := make(map[string]User, len(list))
+	for _, user := range list {
+		users[user.Name] = user
+	}
+	u.users, u.modTime = users, info.ModTime()
+	return nil
+}
+
+// save writes the file atomically. Callers hold mu.
+func (u *Users) save() error {
+	list := make([]User, 0, len(u.users))
+	for _, user := range u.users {
+		list = append(list, user)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(u.path), ".users-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), u.path); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(u.path); err == nil {
+		u.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// HashPassword derives a PBKDF2-SHA256 hash stored as
+// "pbkdf2-sha256$<iterations>$<salt>$<hash>".
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+func checkPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$600000$") {
+		t.Errorf("hash = %q", hash)
+	}
+	if !checkPassword("correct horse", hash) {
+		t.Error("the hashed password does not verify")
+	}
+
+	for _, wrong := range []string{"", "correct horse ", "Correct horse", "battery staple"} {
+		if checkPassword(wrong, hash) {
+			t.Errorf("password %q verifies", wrong)
+		}
+	}
+
+	// Same password, new salt.
+	again, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == hash {
+		t.Error("two hashes of one password are equal")
+	}
+}
+
+func TestCheckPasswordMalformedHash(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		dummyHash[:len(dummyHash)-1] + "$",
+		"bcrypt$10$salt$hash",
+		"pbkdf2-sha256$0$AAAA$AAAA",
+		"pbkdf2-sha256$x$AAAA$AAAA",
+		"pbkdf2-sha256$1000$!!$AAAA",
+	} {
+		if checkPassword("", encoded) {
+			t.Errorf("checkPassword accepted %q", encoded)
+		}
+	}
+}
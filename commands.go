@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"omnituan.online/auth"
+	"omnituan.online/controllers"
+	"omnituan.online/i18n"
+	"omnituan.online/jobs"
+	"omnituan.online/models"
+	"omnituan.online/pnl"
+	"omnituan.online/server"
+	"omnituan.online/services"
+	"omnituan.online/vault"
+)
+
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", cfg.Addr, "listen address")
+	fs.Parse(args)
+
+	if cfg.Vault.Key == "" {
+		return errors.New("BERRIO_VAULT_KEY is required to store seller tokens")
+	}
+	key, err := vault.ParseKey(cfg.Vault.Key)
+	if err != nil {
+		return err
+	}
+	sellerVault, err := vault.Open(cfg.Vault.File, key)
+	if err != nil {
+		return err
+	}
+	controllers.SetSellerVault(sellerVault)
+
+	if cfg.Auth.Secret == "" {
+		return errors.New("BERRIO_AUTH_SECRET is required to sign API tokens")
+	}
+	issuer, err := auth.NewIssuer([]byte(cfg.Auth.Secret), time.Duration(cfg.Auth.TokenTTL))
+	if err != nil {
+		return err
+	}
+	users, err := auth.OpenUsers(cfg.Auth.UsersFile)
+	if err != nil {
+		return fmt.Errorf("cannot open users: %w", err)
+	}
+	controllers.SetAuth(issuer, users)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// sync.keys, and with sync.sellers every registered seller, are kept up
+	// to date in the background.
+	if len(cfg.Sync.Keys) > 0 || cfg.Sync.Sellers {
+		go services.RunReportSync(ctx, func() []services.Seller {
+			return syncSellers(sellerVault)
+		}, time.Duration(cfg.Sync.Interval))
+	}
+
+	reportJobs := jobs.NewManager(cfg.Jobs.Workers, cfg.Jobs.QueueSize, time.Duration(cfg.Jobs.TTL))
+	defer reportJobs.Close()
+	controllers.SetReportJobs(reportJobs)
+	controllers.SetReportDefaults(cfg.Reports)
+
+	router := server.NewRouter(server.Options{
+		Issuer:       issuer,
+		Users:        users,
+		CORSOrigins:  cfg.CORS.AllowOrigins,
+		Metrics:      cfg.Metrics.Enabled,
+		MetricsToken: cfg.Metrics.Token,
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           router,
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.ListenAndServe()
+	}()
+	slog.Info("Server started", "url", "http://localhost"+*addr)
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal kills the process without waiting.
+	stop()
+	shutdown(srv, reportJobs, time.Duration(cfg.Server.ShutdownTimeout))
+	return nil
+}
+
+// shutdown stops taking requests and jobs, then waits up to timeout for
+// in-flight requests and report jobs before cutting them off.
+func shutdown(srv *http.Server, reportJobs *jobs.Manager, timeout time.Duration) {
+	stats := reportJobs.Stats()
+	slog.Info("Shutting down", "timeout", timeout, "running_jobs", stats.Running, "queued_jobs", stats.Queued)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var serverErr, jobsErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if serverErr = srv.Shutdown(ctx); serverErr != nil {
+			srv.Close()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		jobsErr = reportJobs.Shutdown(ctx)
+	}()
+	wg.Wait()
+
+	if serverErr != nil || jobsErr != nil {
+		slog.Warn("Shutdown deadline passed, cancelled remaining work", "requests", serverErr, "jobs", jobsErr)
+		return
+	}
+	slog.Info("Server stopped")
+}
+
+// fetchCommand saves the realization rows of a period as a JSON array that
+// the report command can read back.
+func fetchCommand(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	apiKey := fs.String("key", os.Getenv("WB_API_KEY"), "WB API key (default $WB_API_KEY)")
+	from := fs.String("from", "", "first day, YYYY-MM-DD")
+	to := fs.String("to", "", "last day, YYYY-MM-DD")
+	out := fs.String("out", "reports.json", "output file")
+	refresh := fs.Bool("refresh", false, "ignore cached weeks")
+	fs.Parse(args)
+
+	if *apiKey == "" {
+		return errors.New("missing -key")
+	}
+	dateFrom, dateTo, err := services.ParsePeriod(*from, *to)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reports, err := services.GetReportDetails(ctx, services.SellerFromAPIKey(*apiKey), dateFrom, dateTo, *refresh, nil)
+	if err != nil {
+		return fmt.Errorf("cannot get reports: %w", err)
+	}
+	if reports == nil {
+		reports = []models.ReportDetails{}
+	}
+	if err := writeJSONFile(*out, reports); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d records to %s\n", len(reports), *out)
+	return nil
+}
+
+// reportCommand builds the Excel reports from a file saved by fetch, so a
+// report can be reproduced without WB access.
+func reportCommand(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	in := fs.String("in", "reports.json", "rows saved by the fetch command")
+	out := fs.String("out", ".", "directory for the xlsx files")
+	tax := fs.Float64("tax", cfg.Reports.Tax, "tax rate")
+	discount := fs.Float64("discount", cfg.Reports.Discount, "markup divisor used to estimate cost of goods")
+	costsFile := fs.String("costs", "", "JSON file with per-SKU cost prices")
+	groupBySize := fs.Bool("group-by-size", false, "split the SKU sheet by size")
+	outputs := fs.String("outputs", services.OutputTotal, "comma separated workbooks: total, detailed")
+	localeFlag := fs.String("locale", string(i18n.Default), "label language: vi, ru, en")
+	fs.Parse(args)
+
+	locale, ok := i18n.Parse(*localeFlag)
+	if !ok {
+		return fmt.Errorf("unknown locale %q", *localeFlag)
+	}
+	opts := services.ArchiveOptions{
+		Params: pnl.Params{Tax: *tax, Discount: *discount, GroupBySize: *groupBySize},
+		Locale: locale,
+	}
+	for _, output := range strings.Split(*outputs, ",") {
+		output = strings.TrimSpace(output)
+		if output != services.OutputTotal && output != services.OutputDetailed {
+			return fmt.Errorf("unknown output %q", output)
+		}
+		opts.Outputs = append(opts.Outputs, output)
+	}
+
+	var reports []models.ReportDetails
+	if err := readJSONFile(*in, &reports); err != nil {
+		return err
+	}
+	if *costsFile != "" {
+		if err := readJSONFile(*costsFile, &opts.Params.Costs); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	paths, err := services.WriteReportFiles(*out, reports, opts)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func ordersCommand(args []string) error {
+	fs := flag.NewFlagSet("orders", flag.ExitOnError)
+	apiKey := fs.String("key", os.Getenv("WB_API_KEY"), "WB API key (default $WB_API_KEY)")
+	from := fs.String("from", "", "period begin as accepted by WB, e.g. 2025-05-01 00:00:00")
+	to := fs.String("to", "", "period end as accepted by WB")
+	out := fs.String("out", "orders.json", "output file")
+	fs.Parse(args)
+
+	if *apiKey == "" || *from == "" || *to == "" {
+		return errors.New("-key, -from and -to are required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	data, err := services.GetOrders(ctx, *apiKey, *from, *to)
+	if err != nil {
+		return fmt.Errorf("cannot get orders: %w", err)
+	}
+	if err := writeJSONFile(*out, data); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d products to %s\n", len(data.ChartData), *out)
+	return nil
+}
+
+// syncCommand runs one incremental sync for the keys given with -key, or
+// sync.keys from the configuration when there are none.
+func syncCommand(args []string) error {
+	var keys []string
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	fs.Func("key", "WB API key to sync (repeatable)", func(v string) error {
+		keys = append(keys, v)
+		return nil
+	})
+	sinceFlag := fs.String("since", "", "first day (YYYY-MM-DD) to sync; days before the synced range are backfilled")
+	fs.Parse(args)
+
+	var since time.Time
+	if *sinceFlag != "" {
+		var err error
+		if since, err = time.Parse("2006-01-02", *sinceFlag); err != nil {
+			return fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+	if len(keys) == 0 {
+		keys = cfg.Sync.Keys
+	}
+	if len(keys) == 0 {
+		return errors.New("no API keys: pass -key or set BERRIO_SYNC_KEYS")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, key := range keys {
+		seller := services.SellerFromAPIKey(key)
+		result, err := services.SyncReports(ctx, seller, since)
+		if err != nil {
+			return fmt.Errorf("seller %s: %w", seller.Key, err)
+		}
+		fmt.Printf("Seller %s: %d new records since %s, last rrdid: %d\n",
+			seller.Key, result.Rows, result.Since.Format("2006-01-02"), result.LastRrdID)
+	}
+	return nil
+}
+
+// userCommand edits the users file read by serve. Flags left out keep the
+// current values of an existing user.
+func userCommand(args []string) error {
+	fs := flag.NewFlagSet("user", flag.ExitOnError)
+	name := fs.String("name", "", "user name")
+	password := fs.String("password", "", "new password (read from stdin when \"-\")")
+	sellers := fs.String("sellers", "", "comma separated seller IDs the user may report on")
+	admin := fs.Bool("admin", false, "allow every seller and managing the vault")
+	remove := fs.Bool("delete", false, "delete the user")
+	fs.Parse(args)
+
+	if *name == "" {
+		return errors.New("missing -name")
+	}
+	users, err := auth.OpenUsers(cfg.Auth.UsersFile)
+	if err != nil {
+		return err
+	}
+	if *remove {
+		if err := users.Delete(*name); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted user %s\n", *name)
+		return nil
+	}
+
+	user, exists := users.Get(*name)
+	user.Name = *name
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if *password == "-" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("cannot read password: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if set["password"] {
+		if *password == "" {
+			return errors.New("password must not be empty")
+		}
+		if user.PasswordHash, err = auth.HashPassword(*password); err != nil {
+			return err
+		}
+	} else if !exists {
+		return errors.New("missing -password for a new user")
+	}
+	if set["sellers"] {
+		user.Sellers = []string{}
+		for _, id := range strings.Split(*sellers, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				user.Sellers = append(user.Sellers, id)
+			}
+		}
+	}
+	if set["admin"] {
+		user.Admin = *admin
+	}
+	if user.Sellers == nil {
+		user.Sellers = []string{}
+	}
+
+	if err := users.Put(user); err != nil {
+		return err
+	}
+	fmt.Printf("Saved user %s (admin: %t, sellers: %s)\n", user.Name, user.Admin, strings.Join(user.Sellers, ","))
+	return nil
+}
+
+func syncSellers(v *vault.Vault) []services.Seller {
+	var sellers []services.Seller
+	for _, key := range cfg.Sync.Keys {
+		sellers = append(sellers, services.SellerFromAPIKey(key))
+	}
+	if !cfg.Sync.Sellers {
+		return sellers
+	}
+	for _, s := range v.List() {
+		token, err := v.Token(s.ID)
+		if err != nil {
+			slog.Warn("Skipping sync of seller", "seller", s.ID, "error", err)
+			continue
+		}
+		sellers = append(sellers, services.Seller{Key: s.ID, APIKey: token})
+	}
+	return sellers
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,369 @@
+// Package config loads the service settings from an optional JSON file and
+// BERRIO_* environment variables, which take precedence over the file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"omnituan.online/auth"
+	"omnituan.online/logging"
+	"omnituan.online/vault"
+	"omnituan.online/wb"
+)
+
+// DefaultFile is read when BERRIO_CONFIG is not set; it may be missing.
+const DefaultFile = "berrio.json"
+
+// MaxReportPageLimit is the largest page reportDetailByPeriod serves.
+const MaxReportPageLimit = 100000
+
+type Config struct {
+	// Addr is the listen address of the HTTP API.
+	Addr string `json:"addr"`
+	// CacheDir holds the local copy of fetched realization rows.
+	CacheDir string `json:"cacheDir"`
+	// FakeWB serves recorded Wildberries payloads for offline demos.
+	FakeWB bool `json:"fakeWB"`
+
+	Server  Server  `json:"server"`
+	WB      WB      `json:"wb"`
+	Reports Reports `json:"reports"`
+	Jobs    Jobs    `json:"jobs"`
+	Sync    Sync    `json:"sync"`
+	Vault   Vault   `json:"vault"`
+	Auth    Auth    `json:"auth"`
+	CORS    CORS    `json:"cors"`
+	Log     Log     `json:"log"`
+	Metrics Metrics `json:"metrics"`
+}
+
+// Server holds the HTTP server timeouts.
+type Server struct {
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	// WriteTimeout bounds a whole response; report exports extend it after
+	// every page they stream.
+	WriteTimeout Duration `json:"writeTimeout"`
+	IdleTimeout  Duration `json:"idleTimeout"`
+	// ShutdownTimeout is how long SIGTERM waits for in-flight requests and
+	// report jobs before cancelling them.
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+}
+
+type WB struct {
+	StatisticsURL string `json:"statisticsURL"`
+	AnalyticsURL  string `json:"analyticsURL"`
+	// StatisticsTimeout of zero waits as long as WB needs for a page.
+	StatisticsTimeout Duration `json:"statisticsTimeout"`
+	AnalyticsTimeout  Duration `json:"analyticsTimeout"`
+	// ReportPageLimit is the number of rows asked per reportDetailByPeriod call.
+	ReportPageLimit int `json:"reportPageLimit"`
+	// Timezone is the zone of the orders analytics period.
+	Timezone string `json:"timezone"`
+	// OrdersPageInterval spaces nm-report/detail calls to stay within the
+	// 3 requests per minute limit.
+	OrdersPageInterval Duration `json:"ordersPageInterval"`
+}
+
+// Reports holds the defaults for fields a report request leaves empty.
+type Reports struct {
+	Tax      float64 `json:"tax"`
+	Discount float64 `json:"discount"`
+}
+
+type Jobs struct {
+	Workers   int      `json:"workers"`
+	QueueSize int      `json:"queueSize"`
+	TTL       Duration `json:"ttl"`
+}
+
+type Sync struct {
+	// Keys are WB API keys synced in the background by the server.
+	Keys []string `json:"keys"`
+	// Sellers also syncs every seller registered in the vault.
+	Sellers  bool     `json:"sellers"`
+	Interval Duration `json:"interval"`
+	// Lookback is how far back the first sync of a key reaches.
+	Lookback Duration `json:"lookback"`
+}
+
+type Vault struct {
+	// File holds the registered sellers with their tokens encrypted.
+	File string `json:"file"`
+	// Key is the base64 AES-256 key sealing the tokens. It is only taken
+	// from BERRIO_VAULT_KEY so it never sits in a file next to the data.
+	Key string `json:"-"`
+}
+
+type Auth struct {
+	// UsersFile holds the API users and the sellers each may report on.
+	UsersFile string `json:"usersFile"`
+	// TokenTTL is how long an issued API token stays valid.
+	TokenTTL Duration `json:"tokenTTL"`
+	// Secret signs the API tokens. Like the vault key it is only taken from
+	// BERRIO_AUTH_SECRET.
+	Secret string `json:"-"`
+}
+
+type CORS struct {
+	// AllowOrigins are the browser origins allowed to call the API. Empty,
+	// the default, only serves pages of the API's own origin; a frontend
+	// elsewhere is opted in by listing it, e.g. ["https://app.example.com"]
+	// or BERRIO_CORS_ALLOW_ORIGINS=https://app.example.com,http://localhost:3000.
+	// "*" allows any origin.
+	AllowOrigins []string `json:"allowOrigins"`
+}
+
+type Log struct {
+	// Level is debug, info, warn or error.
+	Level string `json:"level"`
+	// Format is text or json.
+	Format string `json:"format"`
+}
+
+type Metrics struct {
+	// Enabled serves the Prometheus metrics at /metrics; off by default.
+	Enabled bool `json:"enabled"`
+	// Token must be sent by scrapers as a bearer token and is required when
+	// Enabled. It is only taken from BERRIO_METRICS_TOKEN.
+	Token string `json:"-"`
+}
+
+// Duration reads "10s"-style strings from JSON and the environment.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		Addr:     ":8080",
+		CacheDir: "data/reports",
+		Server: Server{
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			WriteTimeout:      Duration(10 * time.Minute),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(time.Minute),
+		},
+		WB: WB{
+			StatisticsURL:      wb.DefaultStatisticsURL,
+			AnalyticsURL:       wb.DefaultAnalyticsURL,
+			AnalyticsTimeout:   Duration(wb.DefaultAnalyticsTimeout),
+			ReportPageLimit:    MaxReportPageLimit,
+			Timezone:           "Europe/Moscow",
+			OrdersPageInterval: Duration(20 * time.Second),
+		},
+		Reports: Reports{Tax: 0.06, Discount: 3.5},
+		Jobs:    Jobs{Workers: 2, QueueSize: 16, TTL: Duration(time.Hour)},
+		Sync:    Sync{Interval: Duration(6 * time.Hour), Lookback: Duration(90 * 24 * time.Hour)},
+		Vault:   Vault{File: "data/sellers.json"},
+		Auth:    Auth{UsersFile: "data/users.json", TokenTTL: Duration(12 * time.Hour)},
+		Log:     Log{Level: "info", Format: logging.FormatText},
+	}
+}
+
+// Load reads the file named by BERRIO_CONFIG (DefaultFile if unset), applies
+// the environment on top and validates the result.
+func Load() (Config, error) {
+	cfg := Default()
+
+	path, explicit := os.LookupEnv("BERRIO_CONFIG")
+	if !explicit {
+		path = DefaultFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+	case err != nil:
+		return cfg, fmt.Errorf("cannot read config: %w", err)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// env lists the variables that override the file, with the field each sets.
+var env = []struct {
+	name  string
+	field func(c *Config) any
+}{
+	{"BERRIO_ADDR", func(c *Config) any { return &c.Addr }},
+	{"BERRIO_CACHE_DIR", func(c *Config) any { return &c.CacheDir }},
+	{"BERRIO_FAKE_WB", func(c *Config) any { return &c.FakeWB }},
+	{"BERRIO_SERVER_READ_HEADER_TIMEOUT", func(c *Config) any { return &c.Server.ReadHeaderTimeout }},
+	{"BERRIO_SERVER_READ_TIMEOUT", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"BERRIO_SERVER_WRITE_TIMEOUT", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"BERRIO_SERVER_IDLE_TIMEOUT", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"BERRIO_SERVER_SHUTDOWN_TIMEOUT", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"BERRIO_WB_STATISTICS_URL", func(c *Config) any { return &c.WB.StatisticsURL }},
+	{"BERRIO_WB_ANALYTICS_URL", func(c *Config) any { return &c.WB.AnalyticsURL }},
+	{"BERRIO_WB_STATISTICS_TIMEOUT", func(c *Config) any { return &c.WB.StatisticsTimeout }},
+	{"BERRIO_WB_ANALYTICS_TIMEOUT", func(c *Config) any { return &c.WB.AnalyticsTimeout }},
+	{"BERRIO_WB_REPORT_PAGE_LIMIT", func(c *Config) any { return &c.WB.ReportPageLimit }},
+	{"BERRIO_WB_TIMEZONE", func(c *Config) any { return &c.WB.Timezone }},
+	{"BERRIO_WB_ORDERS_PAGE_INTERVAL", func(c *Config) any { return &c.WB.OrdersPageInterval }},
+	{"BERRIO_REPORTS_TAX", func(c *Config) any { return &c.Reports.Tax }},
+	{"BERRIO_REPORTS_DISCOUNT", func(c *Config) any { return &c.Reports.Discount }},
+	{"BERRIO_JOBS_WORKERS", func(c *Config) any { return &c.Jobs.Workers }},
+	{"BERRIO_JOBS_QUEUE_SIZE", func(c *Config) any { return &c.Jobs.QueueSize }},
+	{"BERRIO_JOBS_TTL", func(c *Config) any { return &c.Jobs.TTL }},
+	{"BERRIO_SYNC_KEYS", func(c *Config) any { return &c.Sync.Keys }},
+	{"BERRIO_SYNC_INTERVAL", func(c *Config) any { return &c.Sync.Interval }},
+	{"BERRIO_SYNC_SELLERS", func(c *Config) any { return &c.Sync.Sellers }},
+	{"BERRIO_SYNC_LOOKBACK", func(c *Config) any { return &c.Sync.Lookback }},
+	{"BERRIO_VAULT_FILE", func(c *Config) any { return &c.Vault.File }},
+	{"BERRIO_VAULT_KEY", func(c *Config) any { return &c.Vault.Key }},
+	{"BERRIO_AUTH_USERS_FILE", func(c *Config) any { return &c.Auth.UsersFile }},
+	{"BERRIO_AUTH_TOKEN_TTL", func(c *Config) any { return &c.Auth.TokenTTL }},
+	{"BERRIO_AUTH_SECRET", func(c *Config) any { return &c.Auth.Secret }},
+	{"BERRIO_CORS_ALLOW_ORIGINS", func(c *Config) any { return &c.CORS.AllowOrigins }},
+	{"BERRIO_LOG_LEVEL", func(c *Config) any { return &c.Log.Level }},
+	{"BERRIO_LOG_FORMAT", func(c *Config) any { return &c.Log.Format }},
+	{"BERRIO_METRICS_ENABLED", func(c *Config) any { return &c.Metrics.Enabled }},
+	{"BERRIO_METRICS_TOKEN", func(c *Config) any { return &c.Metrics.Token }},
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, e := range env {
+		v, ok := lookup(e.name)
+		if !ok {
+			continue
+		}
+		if err := set(e.field(c), v); err != nil {
+			return fmt.Errorf("invalid %s: %w", e.name, err)
+		}
+	}
+	return nil
+}
+
+func set(field any, v string) error {
+	var err error
+	switch p := field.(type) {
+	case *string:
+		*p = v
+	case *bool:
+		*p, err = strconv.ParseBool(v)
+	case *int:
+		*p, err = strconv.Atoi(v)
+	case *float64:
+		*p, err = strconv.ParseFloat(v, 64)
+	case *Duration:
+		var d time.Duration
+		d, err = time.ParseDuration(v)
+		*p = Duration(d)
+	case *[]string:
+		*p = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				*p = append(*p, s)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("config: unsupported field type %T", field))
+	}
+	return err
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Addr != "", "addr must not be empty")
+	check(c.CacheDir != "", "cacheDir must not be empty")
+	for _, t := range []struct {
+		name string
+		d    Duration
+	}{
+		{"server.readHeaderTimeout", c.Server.ReadHeaderTimeout},
+		{"server.readTimeout", c.Server.ReadTimeout},
+		{"server.writeTimeout", c.Server.WriteTimeout},
+		{"server.idleTimeout", c.Server.IdleTimeout},
+		{"server.shutdownTimeout", c.Server.ShutdownTimeout},
+	} {
+		check(t.d > 0, "%s must be positive", t.name)
+	}
+	for _, u := range []struct{ name, raw string }{
+		{"wb.statisticsURL", c.WB.StatisticsURL},
+		{"wb.analyticsURL", c.WB.AnalyticsURL},
+	} {
+		parsed, err := url.Parse(u.raw)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "",
+			"%s must be an absolute http(s) URL, got %q", u.name, u.raw)
+	}
+	check(c.WB.StatisticsTimeout >= 0, "wb.statisticsTimeout must not be negative")
+	check(c.WB.AnalyticsTimeout > 0, "wb.analyticsTimeout must be positive")
+	check(c.WB.ReportPageLimit > 0 && c.WB.ReportPageLimit <= MaxReportPageLimit,
+		"wb.reportPageLimit must be between 1 and %d", MaxReportPageLimit)
+	_, err := time.LoadLocation(c.WB.Timezone)
+	check(c.WB.Timezone != "" && err == nil, "wb.timezone %q is not a known time zone", c.WB.Timezone)
+	check(c.WB.OrdersPageInterval >= 0, "wb.ordersPageInterval must not be negative")
+	check(c.Reports.Tax >= 0 && c.Reports.Tax < 1, "reports.tax must be in [0, 1)")
+	check(c.Reports.Discount > 0, "reports.discount must be positive")
+	check(c.Jobs.Workers > 0, "jobs.workers must be positive")
+	check(c.Jobs.QueueSize > 0, "jobs.queueSize must be positive")
+	check(c.Jobs.TTL > 0, "jobs.ttl must be positive")
+	check(c.Sync.Interval > 0, "sync.interval must be positive")
+	check(c.Sync.Lookback > 0, "sync.lookback must be positive")
+	check(c.Vault.File != "", "vault.file must not be empty")
+	if c.Vault.Key != "" {
+		_, err := vault.ParseKey(c.Vault.Key)
+		check(err == nil, "BERRIO_VAULT_KEY: %v", err)
+	}
+	check(c.Auth.UsersFile != "", "auth.usersFile must not be empty")
+	check(c.Auth.TokenTTL > 0, "auth.tokenTTL must be positive")
+	check(c.Auth.Secret == "" || len(c.Auth.Secret) >= auth.MinSecretSize,
+		"BERRIO_AUTH_SECRET must be at least %d bytes", auth.MinSecretSize)
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" && parsed.Path == "",
+			"cors.allowOrigins: %q is not an origin like https://example.com", origin)
+	}
+	_, err = logging.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level %q must be debug, info, warn or error", c.Log.Level)
+	check(c.Log.Format == logging.FormatText || c.Log.Format == logging.FormatJSON,
+		"log.format %q must be %s or %s", c.Log.Format, logging.FormatText, logging.FormatJSON)
+	check(!c.Metrics.Enabled || c.Metrics.Token != "", "metrics.enabled requires BERRIO_METRICS_TOKEN")
+
+	return errors.Join(errs...)
+}
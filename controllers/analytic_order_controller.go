@@ -4,11 +4,13 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"omnituan.online/middleware"
 	"omnituan.online/services"
+	"omnituan.online/wb"
 )
 
 type AnalyticOrderRequest struct {
-	APIKey   string `form:"apiKey" binding:"required"`
+	SellerID string `form:"sellerId" binding:"required"`
 	DateFrom string `form:"dateFrom" binding:"required"`
 	DateTo   string `form:"dateTo" binding:"required"`
 }
@@ -19,21 +21,37 @@ type AnalyticOrderRequest struct {
 // @Accept       json
 // @Produce      application/json
 // @Param        request  body      AnalyticOrderRequest  true  "Report request parameters"
-// @Success      200      {object}  []services.ChartData
-// @Failure      400      {object}  map[string]string  "Invalid request parameters or date format"
-// @Failure      500      {object}  map[string]string  "Internal server error"
+// @Success      200      {object}  services.OrdersResponse
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters or date format"
+// @Failure      401      {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403      {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404      {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      422      {object}  middleware.ErrorResponse  "WB token expired, lacking the analytics scope or rejected by WB"
+// @Failure      429      {object}  middleware.ErrorResponse  "WB rate limit exceeded"
+// @Failure      500      {object}  middleware.ErrorResponse  "Internal server error"
+// @Failure      502      {object}  middleware.ErrorResponse  "WB failed or answered with an error"
+// @Failure      504      {object}  middleware.ErrorResponse  "WB did not answer in time"
+// @Security     BearerAuth
 // @Router       /orders [post]
 func GetOrdersReport(c *gin.Context) {
 	var req AnalyticOrderRequest
 
 	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid apiKey, dateTo, dateFrom"})
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, "Invalid sellerId, dateTo, dateFrom")
+		return
+	}
+	seller, ok := sellerCredentials(c, req.SellerID)
+	if !ok {
+		return
+	}
+	if !requireScope(c, seller, wb.ScopeAnalytics) {
 		return
 	}
 
-	data, err := services.GetOrders(req.APIKey, req.DateFrom, req.DateTo)
+	data, err := services.GetOrders(c.Request.Context(), seller.APIKey, req.DateFrom, req.DateTo)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error get orders reports"})
+		c.Error(err)
+		return
 	}
 
 	c.JSON(http.StatusOK, data)
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/auth"
+	"omnituan.online/middleware"
+)
+
+var (
+	tokenIssuer *auth.Issuer
+	apiUsers    *auth.Users
+)
+
+// SetAuth installs the token issuer and the users allowed to log in.
+func SetAuth(issuer *auth.Issuer, users *auth.Users) {
+	tokenIssuer = issuer
+	apiUsers = users
+}
+
+type TokenCredentials struct {
+	Username string `form:"username" binding:"required"`
+	Password string `form:"password" binding:"required"`
+}
+
+type AccessToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// @Summary      Get an API token
+// @Description  Exchanges a username and password for a bearer token used by the other endpoints
+// @Tags         auth
+// @Accept       json
+// @Produce      application/json
+// @Param        request  body      TokenCredentials  true  "User credentials"
+// @Success      200      {object}  AccessToken
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters"
+// @Failure      401      {object}  middleware.ErrorResponse  "Wrong username or password"
+// @Router       /auth/token [post]
+func IssueToken(c *gin.Context) {
+	var req TokenCredentials
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	user, ok := apiUsers.Authenticate(req.Username, req.Password)
+	if !ok {
+		middleware.AbortWithError(c, http.StatusUnauthorized, middleware.CodeUnauthorized, "Wrong username or password")
+		return
+	}
+
+	token, expiresAt, err := tokenIssuer.Issue(user.Name, time.Now())
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Failed to issue token")
+		return
+	}
+	c.JSON(http.StatusOK, AccessToken{Token: token, ExpiresAt: expiresAt.UTC()})
+}
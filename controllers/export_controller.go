@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/export"
+	"omnituan.online/middleware"
+	"omnituan.online/models"
+	"omnituan.online/services"
+	"omnituan.online/wb"
+)
+
+type ExportRequest struct {
+	SellerID string `form:"sellerId" binding:"required"`
+	DateFrom string `form:"dateFrom" binding:"required"`
+	DateTo   string `form:"dateTo" binding:"required"`
+	// Format is csv (default) or ndjson.
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson" enums:"csv,ndjson"`
+	// Delimiter is a single CSV field separator; "\t" means tab.
+	Delimiter string `form:"delimiter" example:";"`
+	// BOM prefixes CSV with a UTF-8 byte order mark for Excel; on by default.
+	BOM *bool `form:"bom"`
+}
+
+// @Summary      Export raw realization rows
+// @Description  Streams the reportDetailByPeriod rows for the period as CSV or NDJSON while they are fetched from WB
+// @Tags         reports
+// @Accept       json
+// @Produce      text/csv,application/x-ndjson
+// @Param        request  body      ExportRequest  true  "Export parameters"
+// @Success      200      {file}    binary         "CSV or NDJSON file"
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters or date format"
+// @Failure      401      {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403      {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404      {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      422      {object}  middleware.ErrorResponse  "WB token expired, lacking the statistics scope or rejected by WB"
+// @Failure      429      {object}  middleware.ErrorResponse  "WB rate limit exceeded"
+// @Failure      502      {object}  middleware.ErrorResponse  "WB failed or answered with an error"
+// @Failure      504      {object}  middleware.ErrorResponse  "WB did not answer in time"
+// @Security     BearerAuth
+// @Router       /reports/export [post]
+func ExportReportDetails(c *gin.Context) {
+	var req ExportRequest
+
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	dateFrom, dateTo, err := services.ParsePeriod(req.DateFrom, req.DateTo)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	delimiter, err := export.ParseDelimiter(req.Delimiter)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	seller, ok := sellerCredentials(c, req.SellerID)
+	if !ok {
+		return
+	}
+	if !requireScope(c, seller, wb.ScopeStatistics) {
+		return
+	}
+
+	opts := export.Options{
+		Format:    export.Format(req.Format),
+		Delimiter: delimiter,
+		BOM:       req.BOM == nil || *req.BOM,
+	}
+
+	// Headers go out with the first page so WB errors before it can still
+	// be reported as JSON.
+	var writer export.Writer
+	start := func() error {
+		c.Header("Content-Type", opts.ContentType())
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="report_%s_%s.%s"`, req.DateFrom, req.DateTo, opts.Extension()))
+		c.Status(http.StatusOK)
+
+		writer, err = export.NewWriter(c.Writer, opts)
+		return err
+	}
+
+	err = services.StreamReportDetails(c.Request.Context(), seller.APIKey, dateFrom, dateTo, func(page []models.ReportDetails) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(page); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		extendWriteDeadline(c)
+		return nil
+	})
+
+	if writer != nil {
+		if err != nil {
+			// The status line is gone; abort so the client sees a broken
+			// transfer instead of a silently truncated file.
+			slog.ErrorContext(c.Request.Context(), "Export failed mid-stream", "error", err)
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Empty period: still send the CSV header row.
+	if err := start(); err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Failed to write export")
+		return
+	}
+	writer.Flush()
+}
+
+// extendWriteDeadline gives the next page a full server write timeout, so
+// exports are limited per page rather than as a whole.
+func extendWriteDeadline(c *gin.Context) {
+	srv, ok := c.Request.Context().Value(http.ServerContextKey).(*http.Server)
+	if !ok || srv.WriteTimeout <= 0 {
+		return
+	}
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(srv.WriteTimeout))
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/jobs"
+	"omnituan.online/services"
+)
+
+type Health struct {
+	Status string `json:"status"`
+}
+
+type Readiness struct {
+	Status string `json:"status"`
+	// Checks maps each dependency to "ok" or the reason it failed.
+	Checks map[string]string `json:"checks"`
+	Jobs   jobs.Stats        `json:"jobs"`
+}
+
+// Healthz answers as long as the process serves HTTP; it checks nothing
+// else so a busy WB or a full job queue never gets the server restarted.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, Health{Status: "ok"})
+}
+
+// Readyz answers 503 while the report cache is not writable, the job queue
+// is full or the server is shutting down, so no new work is routed here.
+func Readyz(c *gin.Context) {
+	res := Readiness{Status: "ready", Checks: map[string]string{}, Jobs: reportJobs.Stats()}
+	for name, err := range map[string]error{
+		"storage": services.CheckReportStore(),
+		"jobs":    reportJobs.Ready(),
+	} {
+		res.Checks[name] = "ok"
+		if err != nil {
+			res.Checks[name] = err.Error()
+			res.Status = "not ready"
+		}
+	}
+
+	status := http.StatusOK
+	if res.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, res)
+}
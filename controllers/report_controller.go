@@ -1,108 +1,280 @@
 package controllers
 
 import (
-	"archive/zip"
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"omnituan.online/config"
+	"omnituan.online/i18n"
+	"omnituan.online/jobs"
+	"omnituan.online/logging"
+	"omnituan.online/middleware"
+	"omnituan.online/pnl"
 	"omnituan.online/services"
+	"omnituan.online/wb"
 )
 
+const (
+	phaseFetching   = "fetching"
+	phaseGenerating = "generating"
+)
+
+var (
+	reportJobs     *jobs.Manager
+	reportDefaults = config.Default().Reports
+)
+
+// SetReportJobs installs the job manager backing the /reports endpoints.
+func SetReportJobs(m *jobs.Manager) {
+	reportJobs = m
+}
+
+// SetReportDefaults sets the tax and discount used when a request omits them.
+func SetReportDefaults(d config.Reports) {
+	reportDefaults = d
+}
+
 type ReportRequest struct {
-	APIKey   string  `form:"apiKey" binding:"required"`
-	DateFrom string  `form:"dateFrom" binding:"required"`
-	DateTo   string  `form:"dateTo" binding:"required"`
-	Tax      float64 `form:"tax" binding:"required"`
-	Discount float64 `form:"discount" binding:"required"`
+	SellerID string `form:"sellerId" binding:"required"`
+	DateFrom string `form:"dateFrom" binding:"required"`
+	DateTo   string `form:"dateTo" binding:"required"`
+	// Tax is the share of revenue paid as tax, in [0, 1); reports.tax of
+	// the config when omitted.
+	Tax *float64 `form:"tax" binding:"omitempty,gte=0,lt=1"`
+	// Discount is the divisor estimating cost of goods from retail price;
+	// reports.discount of the config when omitted.
+	Discount *float64 `form:"discount" binding:"omitempty,gt=0"`
+	// Costs are per-unit purchase prices; SKUs missing from it fall back to
+	// the discount divisor.
+	Costs []pnl.CostPrice `form:"costs" binding:"omitempty,dive"`
+	// GroupBySize splits the per-SKU sheet by size (TsName).
+	GroupBySize bool `form:"groupBySize"`
+	// Outputs selects the workbooks in the ZIP: "total" (default) and/or
+	// "detailed", the translated realization report.
+	Outputs []string `form:"outputs" binding:"omitempty,dive,oneof=total detailed" enums:"total,detailed"`
+	// Locale is the language of the report labels; vi by default.
+	Locale string `form:"locale" binding:"omitempty,oneof=vi ru en" enums:"vi,ru,en"`
+	// Refresh downloads the whole period from WB again instead of reusing
+	// cached weeks.
+	Refresh bool `form:"refresh"`
+	// Compare adds the change against the previous period of equal length
+	// ("previous") or the same dates a year earlier ("year").
+	Compare string `form:"compare" binding:"omitempty,oneof=previous year" enums:"previous,year"`
 }
 
-// @Summary      Generate and download report files
-// @Description  Generates two Excel report files based on API key and date range, zips them, and returns the ZIP file for download
+func (r ReportRequest) params() pnl.Params {
+	return pnl.Params{Tax: *r.Tax, Discount: *r.Discount, Costs: r.Costs, GroupBySize: r.GroupBySize}
+}
+
+func (r ReportRequest) archiveOptions() services.ArchiveOptions {
+	locale, _ := i18n.Parse(r.Locale)
+	return services.ArchiveOptions{Params: r.params(), Outputs: r.Outputs, Locale: locale}
+}
+
+// @Summary      Start a report job
+// @Description  Queues generation of the Excel reports for the API key and date range and returns the job to poll; with compare set, the total report gets a Comparison sheet
 // @Tags         reports
 // @Accept       json
-// @Produce      application/zip
+// @Produce      application/json
 // @Param        request  body      ReportRequest  true  "Report request parameters"
-// @Success      200      {file}    binary         "ZIP file containing report1.xlsx and report2.xlsx"
-// @Failure      400      {object}  map[string]string  "Invalid request parameters or date format"
-// @Failure      500      {object}  map[string]string  "Internal server error"
+// @Success      202      {object}  jobs.Snapshot
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters or date format"
+// @Failure      401      {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403      {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404      {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      422      {object}  middleware.ErrorResponse  "WB token expired or lacking the statistics scope"
+// @Failure      503      {object}  middleware.ErrorResponse  "Too many report jobs in progress"
+// @Security     BearerAuth
 // @Router       /reports [post]
 func HandleReportRequest(c *gin.Context) {
-	var req ReportRequest
-
-	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	req, dateFrom, dateTo, ok := bindReportRequest(c)
+	if !ok {
 		return
 	}
-
-	if req.Tax == 0 {
-		req.Tax = 0.06
+	seller, ok := sellerCredentials(c, req.SellerID)
+	if !ok {
+		return
 	}
-	if req.Discount == 0 {
-		req.Discount = 3.5
+	if !requireScope(c, seller, wb.ScopeStatistics) {
+		return
 	}
 
-	dateFrom, err := time.Parse("2006-01-02", req.DateFrom)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dateFrom format. Use YYYY-MM-DD"})
+	user, _ := middleware.CurrentUser(c)
+	requestID := logging.RequestID(c.Request.Context())
+	job, err := reportJobs.Submit(user.Name, func(ctx context.Context, job *jobs.Job, w io.Writer) error {
+		// Log the job's WB calls under the request that queued it.
+		ctx = logging.WithRequestID(ctx, requestID)
+		job.SetPhase(phaseFetching)
+		reports, err := services.GetReportDetails(ctx, seller, dateFrom, dateTo, req.Refresh, job.AddPage)
+		if err != nil {
+			return fmt.Errorf("cannot get reports: %w", err)
+		}
+		opts := req.archiveOptions()
+		opts.Baseline, err = services.GetBaseline(ctx, seller, req.Compare, dateFrom, dateTo, req.Refresh, job.AddPage)
+		if err != nil {
+			return fmt.Errorf("cannot get comparison reports: %w", err)
+		}
+
+		job.SetPhase(phaseGenerating)
+		return services.WriteReportArchive(w, reports, opts)
+	})
+	if errors.Is(err, jobs.ErrQueueFull) || errors.Is(err, jobs.ErrClosed) {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, middleware.CodeUnavailable, "Too many report jobs in progress, try again later")
 		return
 	}
-	dateTo, err := time.Parse("2006-01-02", req.DateTo)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dateTo format. Use YYYY-MM-DD"})
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Failed to start report job")
 		return
 	}
 
-	reports, err := services.GetReportDetails(req.APIKey, dateFrom, dateTo)
+	slog.InfoContext(c.Request.Context(), "Queued report job", "job", job.ID, "seller", req.SellerID)
+	c.Header("Location", fmt.Sprintf("%s/%s", c.FullPath(), job.ID))
+	c.JSON(http.StatusAccepted, job.Snapshot())
+}
+
+// @Summary      Get the P&L summary as JSON
+// @Description  Computes the figures of the total report (summary and per-category tables) without building the Excel file; with compare set, also their change against the compared period
+// @Tags         reports
+// @Accept       json
+// @Produce      application/json
+// @Param        request  body      ReportRequest  true  "Report request parameters"
+// @Success      200      {object}  pnl.Result
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters or date format"
+// @Failure      401      {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403      {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404      {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      422      {object}  middleware.ErrorResponse  "WB token expired, lacking the statistics scope or rejected by WB"
+// @Failure      429      {object}  middleware.ErrorResponse  "WB rate limit exceeded"
+// @Failure      502      {object}  middleware.ErrorResponse  "WB failed or answered with an error"
+// @Failure      504      {object}  middleware.ErrorResponse  "WB did not answer in time"
+// @Security     BearerAuth
+// @Router       /reports/summary [post]
+func GetReportSummary(c *gin.Context) {
+	req, dateFrom, dateTo, ok := bindReportRequest(c)
+	if !ok {
+		return
+	}
+	seller, ok := sellerCredentials(c, req.SellerID)
+	if !ok {
+		return
+	}
+	if !requireScope(c, seller, wb.ScopeStatistics) {
+		return
+	}
+
+	reports, err := services.GetReportDetails(c.Request.Context(), seller, dateFrom, dateTo, req.Refresh, nil)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	baseline, err := services.GetBaseline(c.Request.Context(), seller, req.Compare, dateFrom, dateTo, req.Refresh, nil)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot get reports"})
+		c.Error(err)
 		return
 	}
-	// fmt.Println("Excel 1")
-	// report1, err1 := services.GenerateDetailedExcel(reports)
-	fmt.Println("Excel 2")
-	report2, err2 := services.GenerateReportExcel(reports, req.Tax, req.Discount)
 
-	// if err1 != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Excel files"})
-	// 	return
-	// }
+	result := services.ComputeReport(reports, req.params(), baseline)
+	c.JSON(http.StatusOK, result.Rounded())
+}
 
-	if err2 != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Excel files"})
+// @Summary      Get report job status
+// @Description  Returns the phase and progress (pages fetched, rows) of a report job
+// @Tags         reports
+// @Produce      application/json
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {object}  jobs.Snapshot
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      404  {object}  middleware.ErrorResponse  "Unknown or expired job"
+// @Security     BearerAuth
+// @Router       /reports/{id} [get]
+func GetReportJob(c *gin.Context) {
+	job, ok := userJob(c)
+	if !ok {
 		return
 	}
 
-	var zipBuffer bytes.Buffer
-	zipWriter := zip.NewWriter(&zipBuffer)
-
-	// fw1, err := zipWriter.Create("report_vi.xlsx")
-	// if err != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip entry 1"})
-	// 	return
-	// }
-	// if _, err := fw1.Write(report1); err != nil {
-	// 	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write file 1 to zip"})
-	// 	return
-	// }
+	c.JSON(http.StatusOK, job.Snapshot())
+}
 
-	fw2, err := zipWriter.Create("report_total.xlsx")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip entry 1"})
+// @Summary      Download report files
+// @Description  Returns the ZIP file produced by a finished report job
+// @Tags         reports
+// @Produce      application/zip
+// @Param        id   path      string  true  "Job ID"
+// @Success      200  {file}    binary  "ZIP file containing report_total.xlsx and/or report_<locale>.xlsx"
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      404  {object}  middleware.ErrorResponse  "Unknown or expired job"
+// @Failure      409  {object}  jobs.Snapshot             "Job has not finished yet"
+// @Failure      500  {object}  middleware.ErrorResponse  "Job failed; WB failures use the codes of /reports/summary"
+// @Security     BearerAuth
+// @Router       /reports/{id}/download [get]
+func DownloadReportJob(c *gin.Context) {
+	job, ok := userJob(c)
+	if !ok {
 		return
 	}
-	if _, err := fw2.Write(report2); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write file 2 to zip"})
+
+	switch job.Status() {
+	case jobs.StatusFailed:
+		c.Error(job.Err())
+		return
+	case jobs.StatusQueued, jobs.StatusRunning:
+		c.JSON(http.StatusConflict, job.Snapshot())
 		return
 	}
 
-	if err := zipWriter.Close(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close zip"})
+	archive, ok := job.Open()
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "Report job not found")
 		return
 	}
+	defer archive.Close()
+
 	c.Header("Content-Type", "application/zip")
 	c.Header("Content-Disposition", `attachment; filename="reports.zip"`)
-	c.Data(http.StatusOK, "application/zip", zipBuffer.Bytes())
+	http.ServeContent(c.Writer, c.Request, "reports.zip", job.FinishedAt(), archive)
+}
+
+// userJob looks up the job in the path, writing a 404 and returning
+// ok=false when it is unknown or was submitted by another user.
+func userJob(c *gin.Context) (*jobs.Job, bool) {
+	job, ok := reportJobs.Get(c.Param("id"))
+	user, _ := middleware.CurrentUser(c)
+	if !ok || (job.Owner != user.Name && !user.Admin) {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "Report job not found")
+		return nil, false
+	}
+	return job, true
+}
+
+// bindReportRequest parses and defaults a ReportRequest, writing a 400 and
+// returning ok=false when it is invalid.
+func bindReportRequest(c *gin.Context) (req ReportRequest, dateFrom, dateTo time.Time, ok bool) {
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, err.Error())
+		return req, dateFrom, dateTo, false
+	}
+
+	if req.Tax == nil {
+		tax := reportDefaults.Tax
+		req.Tax = &tax
+	}
+	if req.Discount == nil {
+		discount := reportDefaults.Discount
+		req.Discount = &discount
+	}
+
+	dateFrom, dateTo, err := services.ParsePeriod(req.DateFrom, req.DateTo)
+	if err != nil {
+		c.Error(err)
+		return req, dateFrom, dateTo, false
+	}
+
+	return req, dateFrom, dateTo, true
 }
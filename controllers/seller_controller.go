@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/middleware"
+	"omnituan.online/services"
+	"omnituan.online/vault"
+	"omnituan.online/wb"
+)
+
+var sellerVault *vault.Vault
+
+// SetSellerVault installs the vault resolving sellerId in requests.
+func SetSellerVault(v *vault.Vault) {
+	sellerVault = v
+}
+
+type SellerRequest struct {
+	Name string `form:"name" binding:"required"`
+	// Token is the WB API token; it is stored encrypted and never returned.
+	Token string `form:"token" binding:"required"`
+}
+
+type TokenRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type TokenValidation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// WBRequestID identifies the rejected WB call.
+	WBRequestID string `json:"wbRequestId,omitempty"`
+}
+
+// @Summary      Register a seller
+// @Description  Stores the seller's WB token encrypted and returns the seller ID to use in report requests
+// @Tags         sellers
+// @Accept       json
+// @Produce      application/json
+// @Param        request  body      SellerRequest  true  "Seller name and WB token"
+// @Success      201      {object}  vault.Seller
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters"
+// @Failure      401      {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403      {object}  middleware.ErrorResponse  "Admin access required"
+// @Failure      422      {object}  middleware.ErrorResponse  "Malformed or expired token"
+// @Security     BearerAuth
+// @Router       /sellers [post]
+func CreateSeller(c *gin.Context) {
+	var req SellerRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := services.CheckToken(req.Token, 0); err != nil {
+		c.Error(err)
+		return
+	}
+
+	seller, err := sellerVault.Add(req.Name, req.Token)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Failed to store seller")
+		return
+	}
+	c.JSON(http.StatusCreated, seller)
+}
+
+// @Summary      List sellers
+// @Description  Returns the sellers the current user may report on
+// @Tags         sellers
+// @Produce      application/json
+// @Success      200  {array}  vault.Seller
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Security     BearerAuth
+// @Router       /sellers [get]
+func ListSellers(c *gin.Context) {
+	user, _ := middleware.CurrentUser(c)
+	sellers := []vault.Seller{}
+	for _, seller := range sellerVault.List() {
+		if user.CanAccess(seller.ID) {
+			sellers = append(sellers, seller)
+		}
+	}
+	c.JSON(http.StatusOK, sellers)
+}
+
+// @Summary      Get a seller
+// @Tags         sellers
+// @Produce      application/json
+// @Param        id   path      string  true  "Seller ID"
+// @Success      200  {object}  vault.Seller
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403  {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404  {object}  middleware.ErrorResponse  "Unknown seller"
+// @Security     BearerAuth
+// @Router       /sellers/{id} [get]
+func GetSeller(c *gin.Context) {
+	if !canAccessSeller(c, c.Param("id")) {
+		return
+	}
+	seller, err := sellerVault.Get(c.Param("id"))
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "Seller not found")
+		return
+	}
+	c.JSON(http.StatusOK, seller)
+}
+
+// @Summary      Rotate a seller token
+// @Description  Replaces the stored WB token of a seller
+// @Tags         sellers
+// @Accept       json
+// @Produce      application/json
+// @Param        id       path      string        true  "Seller ID"
+// @Param        request  body      TokenRequest  true  "New WB token"
+// @Success      200      {object}  vault.Seller
+// @Failure      400      {object}  middleware.ErrorResponse  "Invalid request parameters"
+// @Failure      401      {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403      {object}  middleware.ErrorResponse  "Admin access required"
+// @Failure      404      {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      422      {object}  middleware.ErrorResponse  "Malformed or expired token"
+// @Security     BearerAuth
+// @Router       /sellers/{id}/token [put]
+func RotateSellerToken(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindBodyWithJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, middleware.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := services.CheckToken(req.Token, 0); err != nil {
+		c.Error(err)
+		return
+	}
+
+	seller, err := sellerVault.Rotate(c.Param("id"), req.Token)
+	if errors.Is(err, vault.ErrNotFound) {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "Seller not found")
+		return
+	}
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Failed to store seller")
+		return
+	}
+	c.JSON(http.StatusOK, seller)
+}
+
+// @Summary      Validate a seller token
+// @Description  Checks the stored token's expiry and asks WB whether it is still accepted
+// @Tags         sellers
+// @Produce      application/json
+// @Param        id   path      string  true  "Seller ID"
+// @Success      200  {object}  TokenValidation
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403  {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404  {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      429  {object}  middleware.ErrorResponse  "WB rate limit exceeded"
+// @Failure      502  {object}  middleware.ErrorResponse  "WB could not be reached"
+// @Failure      504  {object}  middleware.ErrorResponse  "WB did not answer in time"
+// @Security     BearerAuth
+// @Router       /sellers/{id}/validate [post]
+func ValidateSellerToken(c *gin.Context) {
+	seller, ok := sellerCredentials(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	err := services.ValidateToken(c.Request.Context(), seller.APIKey)
+	var serviceErr *services.Error
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, TokenValidation{Valid: true})
+	case services.IsTokenError(err):
+		c.JSON(http.StatusOK, TokenValidation{Error: err.Error()})
+	case errors.Is(err, services.ErrUpstreamUnauthorized) && errors.As(err, &serviceErr):
+		c.JSON(http.StatusOK, TokenValidation{Error: serviceErr.Message(), WBRequestID: serviceErr.RequestID})
+	default:
+		c.Error(err)
+	}
+}
+
+// @Summary      Inspect a seller token
+// @Description  Decodes the stored WB token and returns its expiry and scopes without calling WB
+// @Tags         sellers
+// @Produce      application/json
+// @Param        id   path      string  true  "Seller ID"
+// @Success      200  {object}  wb.TokenInfo
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403  {object}  middleware.ErrorResponse  "Seller not assigned to the user"
+// @Failure      404  {object}  middleware.ErrorResponse  "Unknown seller"
+// @Failure      422  {object}  middleware.ErrorResponse  "Stored token is not a WB API token"
+// @Security     BearerAuth
+// @Router       /sellers/{id}/token [get]
+func InspectSellerToken(c *gin.Context) {
+	seller, ok := sellerCredentials(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	info, err := wb.ParseToken(seller.APIKey)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// @Summary      Delete a seller
+// @Description  Removes the seller and its stored token
+// @Tags         sellers
+// @Param        id   path  string  true  "Seller ID"
+// @Success      204
+// @Failure      401  {object}  middleware.ErrorResponse  "Missing or invalid bearer token"
+// @Failure      403  {object}  middleware.ErrorResponse  "Admin access required"
+// @Failure      404  {object}  middleware.ErrorResponse  "Unknown seller"
+// @Security     BearerAuth
+// @Router       /sellers/{id} [delete]
+func DeleteSeller(c *gin.Context) {
+	err := sellerVault.Delete(c.Param("id"))
+	if errors.Is(err, vault.ErrNotFound) {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "Seller not found")
+		return
+	}
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Failed to delete seller")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// sellerCredentials resolves a seller ID to its token, writing a 403 or 404
+// and returning ok=false when the user may not use it or it is unknown.
+func sellerCredentials(c *gin.Context, id string) (services.Seller, bool) {
+	if !canAccessSeller(c, id) {
+		return services.Seller{}, false
+	}
+	token, err := sellerVault.Token(id)
+	if errors.Is(err, vault.ErrNotFound) {
+		middleware.AbortWithError(c, http.StatusNotFound, middleware.CodeNotFound, "Seller not found")
+		return services.Seller{}, false
+	}
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, middleware.CodeInternal, "Cannot read seller token")
+		return services.Seller{}, false
+	}
+	return services.Seller{Key: id, APIKey: token}, true
+}
+
+// canAccessSeller writes a 403 and returns false when the current user is
+// not assigned the seller.
+func canAccessSeller(c *gin.Context, id string) bool {
+	user, _ := middleware.CurrentUser(c)
+	if !user.CanAccess(id) {
+		middleware.AbortWithError(c, http.StatusForbidden, middleware.CodeForbidden, "Seller is not assigned to this user")
+		return false
+	}
+	return true
+}
+
+// requireScope records the token error and returns false when the seller's
+// WB token is malformed, expired or lacks need.
+func requireScope(c *gin.Context, seller services.Seller, need wb.Scope) bool {
+	if err := services.CheckToken(seller.APIKey, need); err != nil {
+		c.Error(err)
+		return false
+	}
+	return true
+}
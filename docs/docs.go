@@ -15,8 +15,59 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/auth/token": {
+            "post": {
+                "description": "Exchanges a username and password for a bearer token used by the other endpoints",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get an API token",
+                "parameters": [
+                    {
+                        "description": "User credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.TokenCredentials"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.AccessToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong username or password",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/orders": {
             "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Generates reports orders",
                 "consumes": [
                     "application/json"
@@ -43,28 +94,61 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/services.ChartData"
-                            }
+                            "$ref": "#/definitions/services.OrdersResponse"
                         }
                     },
                     "400": {
                         "description": "Invalid request parameters or date format",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "WB token expired, lacking the analytics scope or rejected by WB",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "WB rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "WB failed or answered with an error",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "WB did not answer in time",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
                         }
                     }
                 }
@@ -72,17 +156,22 @@ const docTemplate = `{
         },
         "/reports": {
             "post": {
-                "description": "Generates two Excel report files based on API key and date range, zips them, and returns the ZIP file for download",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Queues generation of the Excel reports for the API key and date range and returns the job to poll; with compare set, the total report gets a Comparison sheet",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/zip"
+                    "application/json"
                 ],
                 "tags": [
                     "reports"
                 ],
-                "summary": "Generate and download report files",
+                "summary": "Start a report job",
                 "parameters": [
                     {
                         "description": "Report request parameters",
@@ -94,9 +183,85 @@ const docTemplate = `{
                         }
                     }
                 ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/jobs.Snapshot"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters or date format",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "WB token expired or lacking the statistics scope",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Too many report jobs in progress",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/export": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams the reportDetailByPeriod rows for the period as CSV or NDJSON while they are fetched from WB",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Export raw realization rows",
+                "parameters": [
+                    {
+                        "description": "Export parameters",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ExportRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "ZIP file containing report1.xlsx and report2.xlsx",
+                        "description": "CSV or NDJSON file",
                         "schema": {
                             "type": "file"
                         }
@@ -104,69 +269,1234 @@ const docTemplate = `{
                     "400": {
                         "description": "Invalid request parameters or date format",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/middleware.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "401": {
+                        "description": "Missing or invalid bearer token",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "WB token expired, lacking the statistics scope or rejected by WB",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "WB rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "WB failed or answered with an error",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "WB did not answer in time",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "controllers.AnalyticOrderRequest": {
-            "type": "object",
-            "required": [
-                "apiKey",
-                "dateFrom",
-                "dateTo"
-            ],
-            "properties": {
-                "apiKey": {
-                    "type": "string"
-                },
-                "dateFrom": {
-                    "type": "string"
-                },
-                "dateTo": {
-                    "type": "string"
+        },
+        "/reports/summary": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Computes the figures of the total report (summary and per-category tables) without building the Excel file; with compare set, also their change against the compared period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Get the P\u0026L summary as JSON",
+                "parameters": [
+                    {
+                        "description": "Report request parameters",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/pnl.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters or date format",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "WB token expired, lacking the statistics scope or rejected by WB",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "WB rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "WB failed or answered with an error",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "WB did not answer in time",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "controllers.ReportRequest": {
-            "type": "object",
-            "required": [
-                "apiKey",
-                "dateFrom",
-                "dateTo",
-                "discount",
-                "tax"
-            ],
-            "properties": {
-                "apiKey": {
-                    "type": "string"
-                },
-                "dateFrom": {
-                    "type": "string"
-                },
-                "dateTo": {
-                    "type": "string"
-                },
-                "discount": {
-                    "type": "number"
-                },
-                "tax": {
-                    "type": "number"
+        "/reports/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the phase and progress (pages fetched, rows) of a report job",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Get report job status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/jobs.Snapshot"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown or expired job",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/{id}/download": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the ZIP file produced by a finished report job",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Download report files",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ZIP file containing report_total.xlsx and/or report_\u003clocale\u003e.xlsx",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown or expired job",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Job has not finished yet",
+                        "schema": {
+                            "$ref": "#/definitions/jobs.Snapshot"
+                        }
+                    },
+                    "500": {
+                        "description": "Job failed; WB failures use the codes of /reports/summary",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sellers": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the sellers the current user may report on",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "List sellers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/vault.Seller"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stores the seller's WB token encrypted and returns the seller ID to use in report requests",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "Register a seller",
+                "parameters": [
+                    {
+                        "description": "Seller name and WB token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.SellerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/vault.Seller"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Malformed or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sellers/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "Get a seller",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/vault.Seller"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes the seller and its stored token",
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "Delete a seller",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sellers/{id}/token": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Decodes the stored WB token and returns its expiry and scopes without calling WB",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "Inspect a seller token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/wb.TokenInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Stored token is not a WB API token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the stored WB token of a seller",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "Rotate a seller token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New WB token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.TokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/vault.Seller"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request parameters",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin access required",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Malformed or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sellers/{id}/validate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the stored token's expiry and asks WB whether it is still accepted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sellers"
+                ],
+                "summary": "Validate a seller token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Seller ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/controllers.TokenValidation"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid bearer token",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Seller not assigned to the user",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown seller",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "WB rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "WB could not be reached",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "WB did not answer in time",
+                        "schema": {
+                            "$ref": "#/definitions/middleware.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "controllers.AccessToken": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.AnalyticOrderRequest": {
+            "type": "object",
+            "required": [
+                "dateFrom",
+                "dateTo",
+                "sellerId"
+            ],
+            "properties": {
+                "dateFrom": {
+                    "type": "string"
+                },
+                "dateTo": {
+                    "type": "string"
+                },
+                "sellerId": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.ExportRequest": {
+            "type": "object",
+            "required": [
+                "dateFrom",
+                "dateTo",
+                "sellerId"
+            ],
+            "properties": {
+                "bom": {
+                    "description": "BOM prefixes CSV with a UTF-8 byte order mark for Excel; on by default.",
+                    "type": "boolean"
+                },
+                "dateFrom": {
+                    "type": "string"
+                },
+                "dateTo": {
+                    "type": "string"
+                },
+                "delimiter": {
+                    "description": "Delimiter is a single CSV field separator; \"\\t\" means tab.",
+                    "type": "string",
+                    "example": ";"
+                },
+                "format": {
+                    "description": "Format is csv (default) or ndjson.",
+                    "type": "string",
+                    "enum": [
+                        "csv",
+                        "ndjson"
+                    ]
+                },
+                "sellerId": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.ReportRequest": {
+            "type": "object",
+            "required": [
+                "dateFrom",
+                "dateTo",
+                "sellerId"
+            ],
+            "properties": {
+                "compare": {
+                    "description": "Compare adds the change against the previous period of equal length\n(\"previous\") or the same dates a year earlier (\"year\").",
+                    "type": "string",
+                    "enum": [
+                        "previous",
+                        "year"
+                    ]
+                },
+                "costs": {
+                    "description": "Costs are per-unit purchase prices; SKUs missing from it fall back to\nthe discount divisor.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.CostPrice"
+                    }
+                },
+                "dateFrom": {
+                    "type": "string"
+                },
+                "dateTo": {
+                    "type": "string"
+                },
+                "discount": {
+                    "description": "Discount is the divisor estimating cost of goods from retail price;\nreports.discount of the config when omitted.",
+                    "type": "number"
+                },
+                "groupBySize": {
+                    "description": "GroupBySize splits the per-SKU sheet by size (TsName).",
+                    "type": "boolean"
+                },
+                "locale": {
+                    "description": "Locale is the language of the report labels; vi by default.",
+                    "type": "string",
+                    "enum": [
+                        "vi",
+                        "ru",
+                        "en"
+                    ]
+                },
+                "outputs": {
+                    "description": "Outputs selects the workbooks in the ZIP: \"total\" (default) and/or\n\"detailed\", the translated realization report.",
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "total",
+                            "detailed"
+                        ]
+                    }
+                },
+                "refresh": {
+                    "description": "Refresh downloads the whole period from WB again instead of reusing\ncached weeks.",
+                    "type": "boolean"
+                },
+                "sellerId": {
+                    "type": "string"
+                },
+                "tax": {
+                    "description": "Tax is the share of revenue paid as tax, in [0, 1); reports.tax of\nthe config when omitted.",
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "controllers.SellerRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "token"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Token is the WB API token; it is stored encrypted and never returned.",
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.TokenCredentials": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.TokenRequest": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "controllers.TokenValidation": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
+                },
+                "wbRequestId": {
+                    "description": "WBRequestID identifies the rejected WB call.",
+                    "type": "string"
+                }
+            }
+        },
+        "jobs.Snapshot": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "pagesFetched": {
+                    "type": "integer"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/jobs.Status"
+                }
+            }
+        },
+        "jobs.Status": {
+            "type": "string",
+            "enum": [
+                "queued",
+                "running",
+                "done",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "StatusQueued",
+                "StatusRunning",
+                "StatusDone",
+                "StatusFailed"
+            ]
+        },
+        "middleware.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is a stable identifier of the failure, e.g. \"wb_rate_limited\".",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "description": "RequestID is this service's ID of the request, also sent as\nX-Request-Id.",
+                    "type": "string"
+                },
+                "wbRequestId": {
+                    "description": "WBRequestID identifies the failed WB call when WB reported one.",
+                    "type": "string"
+                }
+            }
+        },
+        "pnl.Comparison": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "Mode is how the compared period was chosen, \"previous\" or \"year\".",
+                    "type": "string"
+                },
+                "skus": {
+                    "description": "SKUs covers every article of either period, biggest profit drop first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.SKUDelta"
+                    }
+                },
+                "summary": {
+                    "description": "Summary follows the order of the summary table.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.FigureDelta"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnl.CostPrice": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "cost": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "nmId": {
+                    "type": "integer"
+                },
+                "saName": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnl.Delta": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "type": "number"
+                },
+                "changePct": {
+                    "description": "ChangePct is Change in percent of the previous value; null when the\nprevious value is zero.",
+                    "type": "number"
+                },
+                "current": {
+                    "type": "number"
+                },
+                "previous": {
+                    "type": "number"
+                }
+            }
+        },
+        "pnl.FigureDelta": {
+            "type": "object",
+            "properties": {
+                "change": {
+                    "type": "number"
+                },
+                "changePct": {
+                    "description": "ChangePct is Change in percent of the previous value; null when the\nprevious value is zero.",
+                    "type": "number"
+                },
+                "current": {
+                    "type": "number"
+                },
+                "figure": {
+                    "type": "string"
+                },
+                "previous": {
+                    "type": "number"
+                }
+            }
+        },
+        "pnl.LogisticsRow": {
+            "type": "object",
+            "properties": {
+                "deliveryRub": {
+                    "type": "number"
+                },
+                "saName": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnl.OtherExpenses": {
+            "type": "object",
+            "properties": {
+                "acceptance": {
+                    "type": "number"
+                },
+                "advertising": {
+                    "type": "number"
+                },
+                "fines": {
+                    "type": "number"
+                },
+                "storage": {
+                    "type": "number"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "pnl.Result": {
+            "type": "object",
+            "properties": {
+                "cancelledLogistics": {
+                    "description": "CancelledLogistics is the subset of Logistics spent on orders the\nbuyer cancelled or did not pick up.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.LogisticsRow"
+                    }
+                },
+                "comparison": {
+                    "description": "Comparison is set when the report was compared with another period.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/pnl.Comparison"
+                        }
+                    ]
+                },
+                "logistics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.LogisticsRow"
+                    }
+                },
+                "otherExpenses": {
+                    "$ref": "#/definitions/pnl.OtherExpenses"
+                },
+                "returns": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.SaleRow"
+                    }
+                },
+                "sales": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.SaleRow"
+                    }
+                },
+                "skus": {
+                    "description": "SKUs is the per-article breakdown, least profitable first.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/pnl.SKURow"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/pnl.Summary"
+                },
+                "unmatchedSkus": {
+                    "description": "UnmatchedSKUs lists sold or returned articles missing from a\nnon-empty cost table.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "pnl.SKUDelta": {
+            "type": "object",
+            "properties": {
+                "cogs": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "forPay": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "logistics": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "netProfit": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "otherDeductions": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "penalties": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "revenue": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "saName": {
+                    "type": "string"
+                },
+                "storage": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "tax": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "tsName": {
+                    "type": "string"
+                },
+                "unitsReturned": {
+                    "$ref": "#/definitions/pnl.Delta"
+                },
+                "unitsSold": {
+                    "$ref": "#/definitions/pnl.Delta"
+                }
+            }
+        },
+        "pnl.SKURow": {
+            "type": "object",
+            "properties": {
+                "cogs": {
+                    "type": "number"
+                },
+                "forPay": {
+                    "type": "number"
+                },
+                "logistics": {
+                    "type": "number"
+                },
+                "netProfit": {
+                    "type": "number"
+                },
+                "otherDeductions": {
+                    "type": "number"
+                },
+                "penalties": {
+                    "type": "number"
+                },
+                "revenue": {
+                    "description": "Revenue and ForPay are net of returns.",
+                    "type": "number"
+                },
+                "saName": {
+                    "type": "string"
+                },
+                "storage": {
+                    "type": "number"
+                },
+                "tax": {
+                    "type": "number"
+                },
+                "tsName": {
+                    "type": "string"
+                },
+                "unitsReturned": {
+                    "type": "integer"
+                },
+                "unitsSold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "pnl.SaleRow": {
+            "type": "object",
+            "properties": {
+                "cogs": {
+                    "type": "number"
+                },
+                "estimated": {
+                    "description": "Estimated is true when COGS came from the discount divisor rather\nthan the cost table.",
+                    "type": "boolean"
+                },
+                "forPay": {
+                    "type": "number"
+                },
+                "retailPrice": {
+                    "type": "number"
+                },
+                "saName": {
+                    "type": "string"
+                }
+            }
+        },
+        "pnl.Summary": {
+            "type": "object",
+            "properties": {
+                "estimatedCOGS": {
+                    "type": "number"
+                },
+                "grossProfit": {
+                    "type": "number"
+                },
+                "grossRevenue": {
+                    "description": "GrossRevenue is the retail price of everything sold.",
+                    "type": "number"
+                },
+                "logisticsExpenses": {
+                    "type": "number"
+                },
+                "netProfit": {
+                    "type": "number"
+                },
+                "netRevenue": {
+                    "description": "NetRevenue is what WB transfers for sales after its fees.",
+                    "type": "number"
+                },
+                "otherExpenses": {
+                    "type": "number"
+                },
+                "reductionInRevenue": {
+                    "description": "ReductionInRevenue is what WB claws back for returns.",
+                    "type": "number"
+                },
+                "revenueExcludingCOGS": {
+                    "type": "number"
+                },
+                "revenueExcludingTaxes": {
+                    "description": "RevenueExcludingTaxes is the retail price of returned items.",
+                    "type": "number"
+                },
+                "tax": {
+                    "description": "Tax is charged on retail revenue, TaxFinal on what WB paid out.",
+                    "type": "number"
+                },
+                "taxFinal": {
+                    "type": "number"
+                },
+                "taxRate": {
+                    "type": "number"
                 }
             }
         },
@@ -192,6 +1522,79 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "services.OrdersResponse": {
+            "type": "object",
+            "properties": {
+                "chartData": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ChartData"
+                    }
+                },
+                "pages": {
+                    "type": "integer"
+                },
+                "totalOrders": {
+                    "type": "integer"
+                },
+                "totalPrevOrders": {
+                    "type": "integer"
+                }
+            }
+        },
+        "vault.Seller": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rotatedAt": {
+                    "type": "string"
+                },
+                "tokenHint": {
+                    "type": "string"
+                }
+            }
+        },
+        "wb.TokenInfo": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sellerId": {
+                    "description": "SellerID is the WB supplier the token belongs to.",
+                    "type": "integer"
+                },
+                "test": {
+                    "description": "Test tokens only work against the WB sandbox.",
+                    "type": "boolean"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Type \"Bearer\" followed by a token from /auth/token.",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
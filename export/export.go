@@ -0,0 +1,209 @@
+// Package export writes raw realization report rows as CSV or NDJSON, one
+// page at a time, so callers never hold the whole period in memory.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"omnituan.online/models"
+)
+
+type Format string
+
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+)
+
+const utf8BOM = "\xEF\xBB\xBF"
+
+type Options struct {
+	Format Format
+	// Delimiter separates CSV fields; zero means a comma.
+	Delimiter rune
+	// BOM prefixes CSV output with a UTF-8 byte order mark so Excel
+	// detects the encoding.
+	BOM bool
+}
+
+func (o Options) ContentType() string {
+	if o.Format == NDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func (o Options) Extension() string {
+	if o.Format == NDJSON {
+		return "ndjson"
+	}
+	return "csv"
+}
+
+// Writer serialises rows to an underlying io.Writer.
+type Writer interface {
+	Write(rows []models.ReportDetails) error
+	// Flush pushes buffered output to the underlying writer.
+	Flush() error
+}
+
+func NewWriter(w io.Writer, opts Options) (Writer, error) {
+	switch opts.Format {
+	case NDJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return &ndjsonWriter{encoder: encoder}, nil
+	case CSV, "":
+		return newCSVWriter(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", opts.Format)
+	}
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(rows []models.ReportDetails) error {
+	for i := range rows {
+		if err := n.encoder.Encode(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	csv    *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, opts Options) (*csvWriter, error) {
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		delimiter = ','
+	}
+	if !validDelimiter(delimiter) {
+		return nil, fmt.Errorf("invalid CSV delimiter %q", delimiter)
+	}
+
+	if opts.BOM {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &csvWriter{csv: csv.NewWriter(w), record: make([]string, len(columns))}
+	c.csv.Comma = delimiter
+	// RFC 4180 line endings.
+	c.csv.UseCRLF = true
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	if err := c.csv.Write(header); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *csvWriter) Write(rows []models.ReportDetails) error {
+	for i := range rows {
+		v := reflect.ValueOf(&rows[i]).Elem()
+		for j, col := range columns {
+			c.record[j] = formatField(v.Field(col.index))
+		}
+		if err := c.csv.Write(c.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Flush() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// ParseDelimiter accepts a single-character delimiter; "\t" and "tab" mean
+// a tab.
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return ',', nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || !validDelimiter(r) {
+		return 0, errors.New("delimiter must be a single character other than a quote or newline")
+	}
+	return r, nil
+}
+
+func validDelimiter(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && r != utf8.RuneError
+}
+
+type column struct {
+	name  string
+	index int
+}
+
+// columns follow the field order of models.ReportDetails, named after their
+// WB JSON keys.
+var columns = func() []column {
+	t := reflect.TypeOf(models.ReportDetails{})
+	cols := make([]column, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = t.Field(i).Name
+		}
+		cols = append(cols, column{name: name, index: i})
+	}
+	return cols
+}()
+
+func formatField(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,145 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"omnituan.online/models"
+)
+
+// tricky holds every character CSV has to quote.
+const tricky = "a,b;\"c\"\nd"
+
+func testRows() []models.ReportDetails {
+	return []models.ReportDetails{
+		{RrdID: 1, SaName: tricky, BrandName: "plain"},
+		{RrdID: 2, SaName: "<b>&", BrandName: " leading space"},
+	}
+}
+
+func writeRows(t *testing.T, opts Options) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(testRows()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func columnNames() []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+func TestCSVFields(t *testing.T) {
+	for _, delimiter := range []rune{',', ';', '\t'} {
+		t.Run(string(delimiter), func(t *testing.T) {
+			out := writeRows(t, Options{Format: CSV, Delimiter: delimiter})
+
+			r := csv.NewReader(strings.NewReader(out))
+			r.Comma = delimiter
+			records, err := r.ReadAll()
+			if err != nil {
+				t.Fatalf("output is not valid CSV: %v\n%s", err, out)
+			}
+			if len(records) != 3 {
+				t.Fatalf("%d records, want a header and 2 rows", len(records))
+			}
+			names := columnNames()
+			if !slices.Equal(records[0], names) {
+				t.Errorf("header = %v", records[0])
+			}
+			rrd, sa, brand := slices.Index(names, "rrd_id"), slices.Index(names, "sa_name"), slices.Index(names, "brand_name")
+			for i, row := range testRows() {
+				got := records[i+1]
+				if got[rrd] != []string{"1", "2"}[i] || got[sa] != row.SaName || got[brand] != row.BrandName {
+					t.Errorf("row %d = rrd_id %q, sa_name %q, brand_name %q; want %d, %q, %q",
+						i, got[rrd], got[sa], got[brand], row.RrdID, row.SaName, row.BrandName)
+				}
+			}
+		})
+	}
+}
+
+func TestCSVQuoting(t *testing.T) {
+	out := writeRows(t, Options{Format: CSV})
+	// Quotes are doubled and the embedded newline becomes CRLF like the
+	// record ends.
+	if want := `"a,b;""c""` + "\r\nd\""; !strings.Contains(out, want) {
+		t.Errorf("output does not hold %q:\n%s", want, out)
+	}
+}
+
+func TestCSVLineEndings(t *testing.T) {
+	out := writeRows(t, Options{Format: CSV})
+	if !strings.HasSuffix(out, "\r\n") {
+		t.Error("output does not end in CRLF")
+	}
+	if lf, crlf := strings.Count(out, "\n"), strings.Count(out, "\r\n"); lf != crlf {
+		t.Errorf("%d line feeds, only %d after a carriage return", lf, crlf)
+	}
+}
+
+func TestCSVBOM(t *testing.T) {
+	header := strings.Join(columnNames(), ";") + "\r\n"
+	tests := []struct {
+		bom  bool
+		want string
+	}{
+		{false, header},
+		{true, utf8BOM + header},
+	}
+	for _, tt := range tests {
+		out := writeRows(t, Options{Format: CSV, Delimiter: ';', BOM: tt.bom})
+		if !strings.HasPrefix(out, tt.want) {
+			t.Errorf("BOM %v: output starts %q, want %q", tt.bom, out[:min(len(out), len(tt.want))], tt.want)
+		}
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	out := writeRows(t, Options{Format: NDJSON})
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	rows := testRows()
+	if len(lines) != len(rows) {
+		t.Fatalf("%d lines, want %d:\n%s", len(lines), len(rows), out)
+	}
+	for i, line := range lines {
+		var got models.ReportDetails
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if got.RrdID != rows[i].RrdID || got.SaName != rows[i].SaName {
+			t.Errorf("line %d = %d %q, want %d %q", i, got.RrdID, got.SaName, rows[i].RrdID, rows[i].SaName)
+		}
+	}
+	if !strings.Contains(out, `"sa_name":"<b>&"`) {
+		t.Error("HTML characters are escaped")
+	}
+}
+
+func TestNewWriterInvalid(t *testing.T) {
+	for _, opts := range []Options{
+		{Format: "xlsx"},
+		{Format: CSV, Delimiter: '"'},
+		{Format: CSV, Delimiter: '\n'},
+	} {
+		if _, err := NewWriter(&bytes.Buffer{}, opts); err == nil {
+			t.Errorf("NewWriter(%+v) succeeded", opts)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package i18n
+
+// Labels of the total report.
+const (
+	SupplierArticle Key = "supplierArticle"
+
+	SalesTitle       Key = "salesTitle"
+	SalesRetailPrice Key = "salesRetailPrice"
+	SalesForPay      Key = "salesForPay"
+
+	ReturnsTitle       Key = "returnsTitle"
+	ReturnsRetailPrice Key = "returnsRetailPrice"
+	ReturnsForPay      Key = "returnsForPay"
+
+	LogisticsTitle Key = "logisticsTitle"
+	LogisticsCost  Key = "logisticsCost"
+
+	CancelledTitle Key = "cancelledTitle"
+	CancelledCost  Key = "cancelledCost"
+
+	OtherTitle       Key = "otherTitle"
+	OtherExpense     Key = "otherExpense"
+	OtherAmount      Key = "otherAmount"
+	OtherFines       Key = "otherFines"
+	OtherStorage     Key = "otherStorage"
+	OtherAdvertising Key = "otherAdvertising"
+	OtherAcceptance  Key = "otherAcceptance"
+	OtherTotal       Key = "otherTotal"
+
+	SummaryTitle                 Key = "summaryTitle"
+	SummaryGrossRevenue          Key = "summaryGrossRevenue"
+	SummaryNetRevenue            Key = "summaryNetRevenue"
+	SummaryReductionInRevenue    Key = "summaryReductionInRevenue"
+	SummaryLogistics             Key = "summaryLogistics"
+	SummaryOtherExpenses         Key = "summaryOtherExpenses"
+	SummaryRevenueExcludingCOGS  Key = "summaryRevenueExcludingCOGS"
+	SummaryCOGS                  Key = "summaryCOGS"
+	SummaryRevenueExcludingTaxes Key = "summaryRevenueExcludingTaxes"
+	SummaryGrossProfit           Key = "summaryGrossProfit"
+	// SummaryTaxRate is a format string taking the rate in percent.
+	SummaryTaxRate   Key = "summaryTaxRate"
+	SummaryTaxFinal  Key = "summaryTaxFinal"
+	SummaryNetProfit Key = "summaryNetProfit"
+
+	UnmatchedTitle Key = "unmatchedTitle"
+
+	SKUTitle           Key = "skuTitle"
+	SKUSize            Key = "skuSize"
+	SKUUnitsSold       Key = "skuUnitsSold"
+	SKUUnitsReturned   Key = "skuUnitsReturned"
+	SKURevenue         Key = "skuRevenue"
+	SKUForPay          Key = "skuForPay"
+	SKUOtherDeductions Key = "skuOtherDeductions"
+	SKUTax             Key = "skuTax"
+	SKUNetProfit       Key = "skuNetProfit"
+	SKUGeneralCosts    Key = "skuGeneralCosts"
+
+	// CompareTitle is a format string taking the first and last day of the
+	// compared period.
+	CompareTitle     Key = "compareTitle"
+	CompareFigure    Key = "compareFigure"
+	CompareCurrent   Key = "compareCurrent"
+	ComparePrevious  Key = "comparePrevious"
+	CompareChange    Key = "compareChange"
+	CompareChangePct Key = "compareChangePct"
+	CompareSKUTitle  Key = "compareSkuTitle"
+
+	// Yes and No print the flags of the detailed report.
+	Yes Key = "yes"
+	No  Key = "no"
+)
+
+var catalogue = map[Key]entry{
+	SupplierArticle: {"Артикул поставщика", "Артикул поставщика", "Supplier article"},
+
+	SalesTitle:       {"BẢNG DOANH THU", "ПРОДАЖИ", "SALES"},
+	SalesRetailPrice: {"Giá đăng bán", "Цена розничная", "Retail price"},
+	SalesForPay:      {"Tiền chuyển cho hàng hóa đã bán chưa bao gồm chi phí logistic và chi phí khác", "К перечислению за проданный товар без учета логистики и прочих расходов", "Payout for goods sold before logistics and other expenses"},
+
+	ReturnsTitle:       {"BẢNG HÀNG MUA BỊ TRẢ LẠI", "ВОЗВРАТЫ", "RETURNS"},
+	ReturnsRetailPrice: {"Giá gốc đăng bán", "Цена розничная", "Retail price"},
+	ReturnsForPay:      {"Giá trả lại", "Удержано за возврат", "Clawed back for return"},
+
+	LogisticsTitle: {"BẢNG PHÍ LOGISTIC", "ЛОГИСТИКА", "LOGISTICS"},
+	LogisticsCost:  {"Chi phí logistic", "Стоимость логистики", "Logistics cost"},
+
+	CancelledTitle: {"BẢNG PHÍ ĐƠN HÀNG BỊ HỦY OR KHÔNG MUA", "ЛОГИСТИКА ОТМЕН И НЕВЫКУПОВ", "CANCELLED OR UNCOLLECTED ORDERS"},
+	CancelledCost:  {"phí vận chuyển hàng trả lại", "Обратная логистика", "Return shipping cost"},
+
+	OtherTitle:       {"BẢNG CHI PHÍ KHÁC", "ПРОЧИЕ РАСХОДЫ", "OTHER EXPENSES"},
+	OtherExpense:     {"Chi phí khác", "Статья расходов", "Expense"},
+	OtherAmount:      {"Số tiền", "Сумма", "Amount"},
+	OtherFines:       {"Tiền phạt", "Штрафы", "Penalties"},
+	OtherStorage:     {"Chi phí lưu trữ", "Хранение", "Storage"},
+	OtherAdvertising: {"Chi phí quảng cáo", "Реклама", "Advertising"},
+	OtherAcceptance:  {"Chi phí chấp nhận", "Платная приемка", "Paid acceptance"},
+	OtherTotal:       {"Tổng", "Итого", "Total"},
+
+	SummaryTitle:                 {"BẢNG TỔNG KẾT", "ИТОГИ", "SUMMARY"},
+	SummaryGrossRevenue:          {"Doanh thu theo giá gốc sản phẩm", "Выручка по розничной цене", "Revenue at retail price"},
+	SummaryNetRevenue:            {"Doanh thu sau khi trừ phí WB", "Выручка после комиссии WB", "Revenue after WB fees"},
+	SummaryReductionInRevenue:    {"Giảm trừ doanh thu(hàng trả lại)", "Уменьшение выручки (возвраты)", "Revenue reduction (returns)"},
+	SummaryLogistics:             {"Chi phí logistic", "Логистика", "Logistics"},
+	SummaryOtherExpenses:         {"Chi phí khác", "Прочие расходы", "Other expenses"},
+	SummaryRevenueExcludingCOGS:  {"Doanh thu chưa trừ giá vốn", "Выручка до вычета себестоимости", "Revenue before cost of goods"},
+	SummaryCOGS:                  {"Giá vốn", "Себестоимость", "Cost of goods"},
+	SummaryRevenueExcludingTaxes: {"Doanh thu giảm trừ thuế", "Возвраты по розничной цене", "Returns at retail price"},
+	SummaryGrossProfit:           {"Lãi trước thuế và chi phí khác", "Прибыль до налогов", "Profit before tax"},
+	SummaryTaxRate:               {"Thuế(%.2f%%)", "Налог (%.2f%%)", "Tax (%.2f%%)"},
+	SummaryTaxFinal:              {"Thuế phải đóng", "Налог к уплате", "Tax payable"},
+	SummaryNetProfit:             {"Lợi nhuận thực nhận về sau khi trừ toàn bộ phí", "Чистая прибыль после всех расходов", "Net profit after all expenses"},
+
+	UnmatchedTitle: {"SKU CHƯA CÓ GIÁ VỐN", "АРТИКУЛЫ БЕЗ СЕБЕСТОИМОСТИ", "SKUS WITHOUT COST PRICE"},
+
+	SKUTitle:           {"BẢNG LÃI LỖ THEO SKU", "ПРИБЫЛЬ ПО АРТИКУЛАМ", "PROFIT BY SKU"},
+	SKUSize:            {"Kích thước", "Размер", "Size"},
+	SKUUnitsSold:       {"Số lượng bán", "Продано, шт.", "Units sold"},
+	SKUUnitsReturned:   {"Số lượng trả lại", "Возвращено, шт.", "Units returned"},
+	SKURevenue:         {"Doanh thu theo giá gốc", "Выручка по розничной цене", "Revenue at retail price"},
+	SKUForPay:          {"Tiền WB chuyển", "К перечислению", "Payout"},
+	SKUOtherDeductions: {"Khấu trừ khác", "Прочие удержания", "Other deductions"},
+	SKUTax:             {"Thuế", "Налог", "Tax"},
+	SKUNetProfit:       {"Lợi nhuận ròng", "Чистая прибыль", "Net profit"},
+	SKUGeneralCosts:    {"Chi phí chung", "Общие расходы", "General costs"},
+
+	CompareTitle:     {"SO SÁNH VỚI KỲ %s – %s", "СРАВНЕНИЕ С ПЕРИОДОМ %s – %s", "COMPARED WITH %s – %s"},
+	CompareFigure:    {"Chỉ tiêu", "Показатель", "Figure"},
+	CompareCurrent:   {"Kỳ này", "Текущий период", "This period"},
+	ComparePrevious:  {"Kỳ so sánh", "Период сравнения", "Compared period"},
+	CompareChange:    {"Chênh lệch", "Изменение", "Change"},
+	CompareChangePct: {"Chênh lệch, %", "Изменение, %", "Change, %"},
+	CompareSKUTitle:  {"SO SÁNH THEO SKU", "СРАВНЕНИЕ ПО АРТИКУЛАМ", "COMPARISON BY SKU"},
+
+	Yes: {"Có", "Да", "Yes"},
+	No:  {"Không", "Нет", "No"},
+}
+
+// detailedHeaders follows the column order of WB's realization report; the
+// Russian labels are WB's own.
+var detailedHeaders = []entry{
+	{"STT", "№", "No."},
+	{"Mã giao hàng", "Номер поставки", "Supply number"},
+	{"Loại sản phẩm", "Предмет", "Subject"},
+	{"Mã hàng", "Код номенклатуры", "Nomenclature code"},
+	{"Thương hiệu", "Бренд", "Brand"},
+	{"Mã nhà cung cấp", "Артикул поставщика", "Supplier article"},
+	{"Tên sản phẩm", "Название", "Name"},
+	{"Kích thước", "Размер", "Size"},
+	{"Mã vạch", "Баркод", "Barcode"},
+	{"Loại tài liệu", "Тип документа", "Document type"},
+	{"Lý do giao dịch", "Обоснование для оплаты", "Payment reason"},
+	{"Ngày đặt hàng", "Дата заказа покупателем", "Order date"},
+	{"Ngày bán", "Дата продажи", "Sale date"},
+	{"Số lượng", "Кол-во", "Quantity"},
+	{"Giá niêm yết", "Цена розничная", "Retail price"},
+	{"Doanh thu Wildberries (đã bán)", "Вайлдберриз реализовал Товар (Пр)", "Sold by Wildberries (Pr)"},
+	{"Giảm giá theo thỏa thuận (%)", "Согласованный продуктовый дисконт, %", "Agreed product discount, %"},
+	{"Khuyến mãi mã giảm (%)", "Промокод %", "Promo code, %"},
+	{"Tổng giảm giá sau thỏa thuận (%)", "Итоговая согласованная скидка, %", "Final agreed discount, %"},
+	{"Giá sau giảm", "Цена розничная с учетом согласованной скидки", "Retail price after agreed discount"},
+	{"Giảm giá do đánh giá (%)", "Размер снижения кВВ из-за рейтинга, %", "Commission reduction for rating, %"},
+	{"Giảm giá do khuyến mãi (%)", "Размер изменения кВВ из-за акции, %", "Commission change for promotion, %"},
+	{"Chiết khấu khách hàng thân thiết (SPP) (%)", "Скидка постоянного Покупателя (СПП), %", "Regular customer discount (SPP), %"},
+	{"Hoa hồng (%)", "Размер кВВ, %", "Commission, %"},
+	{"Hoa hồng cơ bản không VAT (%)", "Размер  кВВ без НДС, % Базовый", "Base commission excl. VAT, %"},
+	{"Hoa hồng cuối không VAT (%)", "Итоговый кВВ без НДС, %", "Final commission excl. VAT, %"},
+	{"Hoa hồng Wildberries (chưa VAT)", "Вознаграждение с продаж до вычета услуг поверенного, без НДС", "Sales reward before agent services, excl. VAT"},
+	{"Hoàn phí giao/hoàn trả", "Возмещение за выдачу и возврат товаров на ПВЗ", "Pickup point issue and return compensation"},
+	{"Phí thanh toán", "Эквайринг/Комиссии за организацию платежей", "Acquiring / payment fees"},
+	{"Tỷ lệ phí thanh toán (%)", "Размер комиссии за эквайринг/Комиссии за организацию платежей, %", "Acquiring fee, %"},
+	{"Hình thức thanh toán", "Тип платежа за Эквайринг/Комиссии за организацию платежей", "Acquiring payment type"},
+	{"Phí Wildberries (chưa VAT)", "Вознаграждение Вайлдберриз (ВВ), без НДС", "Wildberries reward, excl. VAT"},
+	{"VAT trên phí Wildberries", "НДС с Вознаграждения Вайлдберриз", "VAT on Wildberries reward"},
+	{"Tiền thực nhận", "К перечислению Продавцу за реализованный Товар", "Payable to seller for goods sold"},
+	{"Số lần giao", "Количество доставок", "Deliveries"},
+	{"Số lần hoàn", "Количество возврата", "Returns"},
+	{"Chi phí giao hàng", "Услуги по доставке товара покупателю", "Delivery to customer"},
+	{"Ngày bắt đầu phí cố định", "Дата начала действия фиксации", "Fixed tariff start date"},
+	{"Ngày kết thúc phí cố định", "Дата конца действия фиксации", "Fixed tariff end date"},
+	{"Dịch vụ giao hàng có tính phí", "Признак услуги платной доставки", "Paid delivery"},
+	{"Tổng tiền phạt", "Общая сумма штрафов", "Total penalties"},
+	{"Điều chỉnh phí Wildberries", "Корректировка Вознаграждения Вайлдберриз (ВВ)", "Wildberries reward adjustment"},
+	{"Loại logistics/phạt/điều chỉnh", "Виды логистики, штрафов и корректировок ВВ", "Logistics, penalty and adjustment type"},
+	{"Mã nhãn dán (Sticker MP)", "Стикер МП", "MP sticker"},
+	{"Ngân hàng thanh toán", "Наименование банка-эквайера", "Acquiring bank"},
+	{"Mã văn phòng", "Номер офиса", "Office number"},
+	{"Tên văn phòng giao hàng", "Наименование офиса доставки", "Delivery office"},
+	{"Mã số thuế đối tác", "ИНН партнера", "Partner INN"},
+	{"Tên đối tác", "Партнер", "Partner"},
+	{"Kho hàng", "Склад", "Warehouse"},
+	{"Quốc gia", "Страна", "Country"},
+	{"Loại hộp", "Тип коробов", "Box type"},
+	{"Số tờ khai hải quan", "Номер таможенной декларации", "Customs declaration number"},
+	{"Mã đơn lắp ráp", "Номер сборочного задания", "Assembly task number"},
+	{"Mã định danh (KIZ)", "Код маркировки", "Marking code"},
+	{"Mã sản phẩm (ШК)", "ШК", "Item barcode (ShK)"},
+	{"Mã giao dịch (Srid)", "Srid", "Srid"},
+	{"Hoàn phí vận chuyển/kho", "Возмещение издержек по перевозке/по складским операциям с товаром", "Transport / warehouse cost reimbursement"},
+	{"Đơn vị vận chuyển", "Организатор перевозки", "Carrier"},
+	{"Phí lưu kho", "Хранение", "Storage"},
+	{"Khoản trừ khác", "Удержания", "Deductions"},
+	{"Phí nhận hàng", "Платная приемка", "Paid acceptance"},
+	{"Hệ số kho cố định", "Фиксированный коэффициент склада по поставке", "Fixed warehouse coefficient"},
+	{"Bán cho công ty", "Признак продажи юридическому лицу", "Sold to legal entity"},
+	{"Số hộp nhận hàng tính phí", "Номер короба для платной приемки", "Paid acceptance box number"},
+	{"Giảm giá đồng tài trợ", "Скидка по программе софинансирования", "Co-financing discount"},
+	{"Giảm giá Wibes (%)", "Скидка Wibes, %", "Wibes discount, %"},
+}
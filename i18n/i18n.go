@@ -0,0 +1,61 @@
+// Package i18n holds the translations of every label printed in the Excel
+// reports. Vietnamese is the reference language.
+package i18n
+
+type Locale string
+
+const (
+	Vietnamese Locale = "vi"
+	Russian    Locale = "ru"
+	English    Locale = "en"
+
+	Default = Vietnamese
+)
+
+type Key string
+
+type entry struct {
+	vi, ru, en string
+}
+
+func (e entry) in(l Locale) string {
+	switch l {
+	case Russian:
+		return e.ru
+	case English:
+		return e.en
+	default:
+		return e.vi
+	}
+}
+
+// Parse returns the locale named by s; empty means Default.
+func Parse(s string) (Locale, bool) {
+	switch l := Locale(s); l {
+	case "":
+		return Default, true
+	case Vietnamese, Russian, English:
+		return l, true
+	default:
+		return Default, false
+	}
+}
+
+// T translates key, falling back to the key itself when it is unknown.
+func (l Locale) T(key Key) string {
+	e, ok := catalogue[key]
+	if !ok {
+		return string(key)
+	}
+	return e.in(l)
+}
+
+// DetailedHeaders returns the column headers of the detailed realization
+// report, in column order.
+func (l Locale) DetailedHeaders() []any {
+	headers := make([]any, len(detailedHeaders))
+	for i, e := range detailedHeaders {
+		headers[i] = e.in(l)
+	}
+	return headers
+}
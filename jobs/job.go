@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Job is one unit of background work. Tasks report progress through the
+// setters; readers take a Snapshot.
+type Job struct {
+	ID string
+	// Owner is the user who submitted the job.
+	Owner string
+
+	mu         sync.RWMutex
+	status     Status
+	phase      string
+	pages      int
+	rows       int
+	err        error
+	resultPath string
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	expiresAt  time.Time
+}
+
+// Snapshot is the JSON view of a job returned by the status endpoint.
+type Snapshot struct {
+	ID           string     `json:"id"`
+	Status       Status     `json:"status"`
+	Phase        string     `json:"phase,omitempty"`
+	PagesFetched int        `json:"pagesFetched"`
+	Rows         int        `json:"rows"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (j *Job) SetPhase(phase string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.phase = phase
+}
+
+// AddPage records one more fetched page holding rows rows.
+func (j *Job) AddPage(rows int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pages++
+	j.rows += rows
+}
+
+func (j *Job) Status() Status {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.status
+}
+
+// Err is the error a failed job ended with.
+func (j *Job) Err() error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.err
+}
+
+// Open returns the task output once the job is done. The caller closes the
+// file; it stays readable even if the job expires meanwhile.
+func (j *Job) Open() (*os.File, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if j.status != StatusDone {
+		return nil, false
+	}
+	f, err := os.Open(j.resultPath)
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// FinishedAt reports when the job completed, zero while it is pending.
+func (j *Job) FinishedAt() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.finishedAt
+}
+
+func (j *Job) Snapshot() Snapshot {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	s := Snapshot{
+		ID:           j.ID,
+		Status:       j.status,
+		Phase:        j.phase,
+		PagesFetched: j.pages,
+		Rows:         j.rows,
+		CreatedAt:    j.createdAt,
+		StartedAt:    timePtr(j.startedAt),
+		FinishedAt:   timePtr(j.finishedAt),
+		ExpiresAt:    timePtr(j.expiresAt),
+	}
+	if j.err != nil {
+		s.Error = j.err.Error()
+	}
+	return s
+}
+
+func (j *Job) start(now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = StatusRunning
+	j.startedAt = now
+}
+
+func (j *Job) finish(resultPath string, err error, now time.Time, ttl time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.status = StatusFailed
+		j.err = err
+	} else {
+		j.status = StatusDone
+		j.phase = ""
+		j.resultPath = resultPath
+	}
+	j.finishedAt = now
+	j.expiresAt = now.Add(ttl)
+}
+
+func (j *Job) expired(now time.Time) bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return !j.expiresAt.IsZero() && now.After(j.expiresAt)
+}
+
+// discard deletes the result file of a finished job.
+func (j *Job) discard() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.resultPath != "" {
+		os.Remove(j.resultPath)
+		j.resultPath = ""
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
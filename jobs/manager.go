@@ -0,0 +1,261 @@
+// Package jobs runs long report generations on a bounded worker pool and
+// keeps their results on disk until they expire.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull = errors.New("job queue is full")
+	ErrClosed    = errors.New("job manager is closed")
+	// ErrCancelled ends the jobs still pending when the manager is closed.
+	ErrCancelled = errors.New("job cancelled")
+)
+
+// Task does the work of a job, writing its downloadable result to w.
+type Task func(ctx context.Context, job *Job, w io.Writer) error
+
+type queued struct {
+	job  *Job
+	task Task
+}
+
+type Manager struct {
+	ttl     time.Duration
+	workers int
+	queue   chan queued
+
+	mu      sync.RWMutex
+	jobs    map[string]*Job
+	closed  bool
+	running int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager starts workers goroutines pulling from a queue of queueSize
+// pending jobs. Finished jobs are forgotten ttl after they complete.
+func NewManager(workers, queueSize int, ttl time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		ttl:     ttl,
+		workers: workers,
+		queue:   make(chan queued, queueSize),
+		jobs:    make(map[string]*Job),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	for range workers {
+		m.wg.Add(1)
+		go m.work()
+	}
+	go m.expire()
+	return m
+}
+
+// Submit queues task and returns its job, or ErrQueueFull when every worker
+// is busy and the queue has no room left.
+func (m *Manager) Submit(owner string, task Task) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{ID: id, Owner: owner, status: StatusQueued, createdAt: time.Now()}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case m.queue <- queued{job: job, task: task}:
+	default:
+		return nil, ErrQueueFull
+	}
+	m.jobs[id] = job
+	return job, nil
+}
+
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// Stats is the state of the worker pool.
+type Stats struct {
+	Workers   int `json:"workers"`
+	Running   int `json:"running"`
+	Queued    int `json:"queued"`
+	QueueSize int `json:"queueSize"`
+}
+
+func (m *Manager) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return Stats{Workers: m.workers, Running: m.running, Queued: len(m.queue), QueueSize: cap(m.queue)}
+}
+
+// Ready returns ErrClosed once the manager is shutting down and
+// ErrQueueFull while Submit would reject new jobs.
+func (m *Manager) Ready() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	switch {
+	case m.closed:
+		return ErrClosed
+	case len(m.queue) == cap(m.queue):
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones
+// to finish. If ctx ends first the remaining jobs fail with ErrCancelled
+// and ctx.Err() is returned. Either way the manager is closed afterwards.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.stop()
+
+	drained := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	m.Close()
+	return err
+}
+
+// Close stops accepting jobs, cancels running ones and waits for the
+// workers to exit.
+func (m *Manager) Close() {
+	m.stop()
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, job := range m.jobs {
+		job.discard()
+		delete(m.jobs, id)
+	}
+}
+
+// stop makes Submit fail and lets the workers exit once the queue is empty.
+func (m *Manager) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+
+	for q := range m.queue {
+		m.setRunning(1)
+		started := time.Now()
+		q.job.start(started)
+		path, err := m.run(q)
+		if err != nil && m.ctx.Err() != nil {
+			err = fmt.Errorf("%w: %w", ErrCancelled, err)
+		}
+		q.job.finish(path, err, time.Now(), m.ttl)
+		m.setRunning(-1)
+
+		if err != nil {
+			slog.Warn("Job failed", "job", q.job.ID, "owner", q.job.Owner, "duration", time.Since(started), "error", err)
+		} else {
+			slog.Info("Job finished", "job", q.job.ID, "owner", q.job.Owner, "duration", time.Since(started))
+		}
+	}
+}
+
+func (m *Manager) setRunning(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running += delta
+}
+
+// run executes the task into a temporary file and returns its path. The
+// file is removed when the task fails.
+func (m *Manager) run(q queued) (path string, err error) {
+	if err := m.ctx.Err(); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "report-job-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create job output: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+		if cerr := f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("failed to close job output: %w", cerr)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			path = ""
+		}
+	}()
+
+	if err := q.task(m.ctx, q.job, f); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (m *Manager) expire() {
+	interval := max(m.ttl/2, time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for id, job := range m.jobs {
+				if job.expired(now) {
+					job.discard()
+					delete(m.jobs, id)
+				}
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
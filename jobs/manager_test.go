@@ -0,0 +1,216 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a few seconds pass.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// blocking returns a task that signals started and then waits for release
+// or the end of its context.
+func blocking(started chan<- string, release <-chan struct{}) Task {
+	return func(ctx context.Context, job *Job, w io.Writer) error {
+		started <- job.ID
+		select {
+		case <-release:
+			_, err := io.WriteString(w, "done")
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	m := NewManager(1, 1, time.Hour)
+	defer m.Close()
+	started, release := make(chan string, 2), make(chan struct{})
+
+	running, err := m.Submit("alice", blocking(started, release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	queuedJob, err := m.Submit("alice", blocking(started, release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Ready(); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Ready = %v, want ErrQueueFull", err)
+	}
+	if _, err := m.Submit("bob", blocking(started, release)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit = %v, want ErrQueueFull", err)
+	}
+	if s := m.Stats(); s.Running != 1 || s.Queued != 1 {
+		t.Errorf("stats = %+v, want 1 running and 1 queued", s)
+	}
+
+	close(release)
+	for _, job := range []*Job{running, queuedJob} {
+		waitFor(t, "job "+job.ID, func() bool { return job.Status() == StatusDone })
+	}
+	if _, err := m.Submit("bob", func(context.Context, *Job, io.Writer) error { return nil }); err != nil {
+		t.Errorf("Submit after the queue drained = %v", err)
+	}
+}
+
+func TestJobExpiry(t *testing.T) {
+	m := NewManager(1, 1, 10*time.Millisecond)
+	defer m.Close()
+
+	job, err := m.Submit("alice", func(_ context.Context, _ *Job, w io.Writer) error {
+		_, err := io.WriteString(w, "report")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the job", func() bool { return job.Status() == StatusDone })
+
+	f, ok := job.Open()
+	if !ok {
+		t.Fatal("Open failed on a done job")
+	}
+	path := f.Name()
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil || string(data) != "report" {
+		t.Errorf("result = %q, %v", data, err)
+	}
+
+	waitFor(t, "the job to expire", func() bool {
+		_, ok := m.Get(job.ID)
+		return !ok
+	})
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("result file after expiry: %v", err)
+	}
+	if _, ok := job.Open(); ok {
+		t.Error("expired job still opens")
+	}
+}
+
+func TestFailedJob(t *testing.T) {
+	m := NewManager(1, 2, time.Hour)
+	defer m.Close()
+	boom := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		task    func() error
+		wantErr string
+	}{
+		{"error", func() error { return boom }, "boom"},
+		{"panic", func() error { panic("nil map") }, "job panicked: nil map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			job, err := m.Submit("alice", func(_ context.Context, _ *Job, w io.Writer) error {
+				path = w.(*os.File).Name()
+				io.WriteString(w, "partial")
+				return tt.task()
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, "the job", func() bool { return job.Status() == StatusFailed })
+
+			if err := job.Err(); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Err = %v, want %s", err, tt.wantErr)
+			}
+			if tt.name == "error" && !errors.Is(job.Err(), boom) {
+				t.Error("Err does not wrap the task error")
+			}
+			if s := job.Snapshot(); s.Error != tt.wantErr || s.FinishedAt == nil {
+				t.Errorf("snapshot = %+v", s)
+			}
+			if _, ok := job.Open(); ok {
+				t.Error("failed job opens")
+			}
+			if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("partial output of the failed job: %v", err)
+			}
+		})
+	}
+}
+
+func TestShutdownDrains(t *testing.T) {
+	m := NewManager(1, 2, time.Hour)
+	started, release := make(chan string, 2), make(chan struct{})
+	var submitted []*Job
+	for range 2 {
+		job, err := m.Submit("alice", blocking(started, release))
+		if err != nil {
+			t.Fatal(err)
+		}
+		submitted = append(submitted, job)
+	}
+	<-started
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	for _, job := range submitted {
+		if job.Status() != StatusDone {
+			t.Errorf("job %s: %s %v, want done", job.ID, job.Status(), job.Err())
+		}
+	}
+	if _, err := m.Submit("alice", blocking(started, release)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Shutdown = %v, want ErrClosed", err)
+	}
+	if err := m.Ready(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Ready after Shutdown = %v, want ErrClosed", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	m := NewManager(1, 2, time.Hour)
+	started, never := make(chan string, 2), make(chan struct{})
+	var submitted []*Job
+	for range 2 {
+		job, err := m.Submit("alice", blocking(started, never))
+		if err != nil {
+			t.Fatal(err)
+		}
+		submitted = append(submitted, job)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+	// Both the running and the queued job are cut off.
+	for _, job := range submitted {
+		if job.Status() != StatusFailed || !errors.Is(job.Err(), ErrCancelled) {
+			t.Errorf("job %s: %s %v, want failed with ErrCancelled", job.ID, job.Status(), job.Err())
+		}
+	}
+	if _, err := m.Submit("alice", blocking(started, never)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Shutdown = %v, want ErrClosed", err)
+	}
+}
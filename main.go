@@ -2,37 +2,114 @@ package main
 
 import (
 	"fmt"
-	"net/http"
+	"log/slog"
+	"os"
+	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"omnituan.online/controllers"
-
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
-	_ "omnituan.online/docs"
+	"omnituan.online/config"
+	"omnituan.online/logging"
+	"omnituan.online/services"
+	"omnituan.online/store"
+	"omnituan.online/wb"
+	"omnituan.online/wb/wbfake"
 )
 
+const usage = `Usage: berrio <command> [flags]
+
+Commands:
+  serve    start the HTTP API (default)
+  fetch    download realization report rows to a JSON file
+  report   build report_total.xlsx / report_<locale>.xlsx from a JSON file
+  orders   download the orders analytics to a JSON file
+  sync     pull new realization rows into the local cache
+  user     add, update or delete an API user
+
+Run "berrio <command> -h" for the flags of a command.
+`
+
+// cfg is loaded before any command runs.
+var cfg config.Config
+
+var commands = map[string]func(args []string) error{
+	"serve":  serveCommand,
+	"fetch":  fetchCommand,
+	"report": reportCommand,
+	"orders": ordersCommand,
+	"sync":   syncCommand,
+	"user":   userCommand,
+}
+
 // @title API Documentation
 // @version         1.0
 // @description     Report Service.
 // @host            localhost:8080
 // @BasePath        /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a token from /auth/token.
 func main() {
-	router := gin.Default()
-	router.Use(cors.Default())
+	os.Exit(run())
+}
 
-	router.GET("/", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "Welcome"})
-	})
+// run executes the command and returns the exit code, so deferred cleanups
+// such as stopping the fake WB server run before the process exits.
+func run() int {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		if name != "help" && name != "-h" && name != "--help" {
+			return 2
+		}
+		return 0
+	}
+
+	var err error
+	if cfg, err = config.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		return 1
+	}
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid configuration:", err)
+		return 1
+	}
+	slog.SetDefault(logger)
 
-	v1 := router.Group("/api/v1")
-	{
-		v1.POST("/reports", controllers.HandleReportRequest)
-		v1.POST("/orders", controllers.GetOrdersReport)
+	wbConfig := wb.Config{
+		StatisticsURL:     cfg.WB.StatisticsURL,
+		AnalyticsURL:      cfg.WB.AnalyticsURL,
+		StatisticsTimeout: time.Duration(cfg.WB.StatisticsTimeout),
+		AnalyticsTimeout:  time.Duration(cfg.WB.AnalyticsTimeout),
 	}
+	services.OrdersPageInterval = time.Duration(cfg.WB.OrdersPageInterval)
+	if cfg.FakeWB {
+		fake := wbfake.NewServer()
+		defer fake.Close()
+		wbConfig.StatisticsURL, wbConfig.AnalyticsURL = fake.URL, fake.URL
+		services.OrdersPageInterval = 0
+		slog.Info("Using fake Wildberries API", "url", fake.URL,
+			"token", wbfake.Token("demo", wb.ScopeStatistics|wb.ScopeAnalytics, time.Now().AddDate(1, 0, 0)))
+	}
+	services.SetWBClient(wb.NewClient(wbConfig))
+	services.ReportPageLimit = cfg.WB.ReportPageLimit
+	services.OrdersTimezone = cfg.WB.Timezone
+	services.SyncLookback = time.Duration(cfg.Sync.Lookback)
+
+	reportStore, err := store.Open(cfg.CacheDir)
+	if err != nil {
+		slog.Error("Cannot open report cache", "error", err)
+		return 1
+	}
+	services.SetReportStore(reportStore)
 
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	fmt.Println("Server started at: http://localhost:8080")
-	router.Run(":8080")
+	if err := command(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
 }
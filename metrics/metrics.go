@@ -0,0 +1,263 @@
+// Package metrics keeps the service's counters and histograms and serves
+// them in the Prometheus text format, so any scraper can collect them
+// without the service depending on a monitoring client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets suit latencies in seconds, from 5ms to 10s.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ExponentialBuckets returns count upper bounds starting at start, each
+// factor times the previous one.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// family is what a metric writes to the exposition.
+type family interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]family{}
+)
+
+// register adds f to the families served by Handler. Metrics are package
+// variables, so a clash is a programming error.
+func register(f family) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[f.name()]; ok {
+		panic("metrics: duplicate metric " + f.name())
+	}
+	registry[f.name()] = f
+}
+
+// desc holds the parts shared by every metric type.
+type desc struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (d desc) name() string { return d.metricName }
+
+func (d desc) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, helpEscaper.Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, kind)
+}
+
+// key identifies a series by its label values.
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", d.metricName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs renders {a="x",b="y"} with extra appended after the metric's
+// own labels.
+func (d desc) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, label, labelEscaper.Replace(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Counter is a monotonically increasing value per label set.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+// NewCounter registers a counter partitioned by labels.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name, help, labels}, series: map[string]*counterSeries{}}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the series of labelValues by v, which must not be negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.metricName + " cannot decrease")
+	}
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: slices.Clone(labelValues)}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(w, "counter")
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, c.labelPairs(s.values), formatFloat(s.value))
+	}
+}
+
+// Histogram counts observations into cumulative buckets per label set.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the given upper bounds, which
+// must be sorted; the +Inf bucket is implied.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !slices.IsSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	h := &Histogram{desc: desc{name, help, labels}, buckets: buckets, series: map[string]*histogramSeries{}}
+	register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(w, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelPairs(s.values, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, h.labelPairs(s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.labelPairs(s.values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.labelPairs(s.values), s.count)
+	}
+}
+
+// WriteText writes every registered metric in the Prometheus text format,
+// sorted by name.
+func WriteText(w io.Writer) error {
+	registryMu.Lock()
+	families := make([]family, 0, len(registry))
+	for _, f := range registry {
+		families = append(families, f)
+	}
+	registryMu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name() < families[j].name() })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves WriteText to scrapers.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// The text format escapes only these characters; Go's %q would escape more.
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
@@ -0,0 +1,65 @@
+// Package middleware holds the gin middleware shared by the API routes.
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/auth"
+)
+
+const userKey = "user"
+
+// Authenticate requires a bearer token issued by issuer for a user that
+// still exists, and stores that user for CurrentUser.
+func Authenticate(issuer *auth.Issuer, users *auth.Users) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Missing bearer token")
+			return
+		}
+
+		claims, err := issuer.Verify(token, time.Now())
+		if errors.Is(err, auth.ErrExpiredToken) {
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Token has expired")
+			return
+		}
+		if err != nil {
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Invalid token")
+			return
+		}
+
+		user, ok := users.Get(claims.Subject)
+		if !ok {
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Unknown user")
+			return
+		}
+		c.Set(userKey, user)
+		c.Next()
+	}
+}
+
+// RequireAdmin lets only admins through; it must run after Authenticate.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, ok := CurrentUser(c); !ok || !user.Admin {
+			AbortWithError(c, http.StatusForbidden, CodeForbidden, "Admin access required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user authenticated for the request.
+func CurrentUser(c *gin.Context) (auth.User, bool) {
+	v, ok := c.Get(userKey)
+	if !ok {
+		return auth.User{}, false
+	}
+	user, ok := v.(auth.User)
+	return user, ok
+}
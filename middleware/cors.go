@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS allows browser calls from origins; "*" allows any origin. With no
+// origins it adds no headers, so browsers keep to the same origin.
+func CORS(origins []string) gin.HandlerFunc {
+	if len(origins) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	config := cors.Config{
+		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
+		ExposeHeaders: []string{"Location", "Content-Disposition"},
+		MaxAge:        12 * time.Hour,
+	}
+	if slices.Contains(origins, "*") {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = origins
+	}
+	return cors.New(config)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    string
+	}{
+		{"same origin only", nil, "https://app.example.com", ""},
+		{"listed origin", []string{"https://app.example.com"}, "https://app.example.com", "https://app.example.com"},
+		{"unlisted origin", []string{"https://app.example.com"}, "https://evil.example.com", ""},
+		{"any origin", []string{"*"}, "https://evil.example.com", "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(CORS(tt.origins))
+			router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
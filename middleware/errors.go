@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/logging"
+	"omnituan.online/services"
+	"omnituan.online/wb"
+)
+
+// Error codes of ErrorResponse not tied to a service error kind.
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeNotFound       = "not_found"
+	CodeUnavailable    = "unavailable"
+	CodeInternal       = "internal"
+)
+
+// statusClientClosedRequest is nginx's non-standard code for a request the
+// client abandoned before the response was ready.
+const statusClientClosedRequest = 499
+
+// ErrorResponse is the body of every failed API call.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Code is a stable identifier of the failure, e.g. "wb_rate_limited".
+	Code string `json:"code"`
+	// WBRequestID identifies the failed WB call when WB reported one.
+	WBRequestID string `json:"wbRequestId,omitempty"`
+	// RequestID is this service's ID of the request, also sent as
+	// X-Request-Id.
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// errorKinds maps the service error kinds to their status and code.
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{services.ErrInvalidPeriod, http.StatusBadRequest, "invalid_period"},
+	{wb.ErrMalformedToken, http.StatusUnprocessableEntity, "token_malformed"},
+	{wb.ErrTokenExpired, http.StatusUnprocessableEntity, "token_expired"},
+	{services.ErrUpstreamUnauthorized, http.StatusUnprocessableEntity, "wb_unauthorized"},
+	{services.ErrRateLimited, http.StatusTooManyRequests, "wb_rate_limited"},
+	{services.ErrUpstreamUnavailable, http.StatusBadGateway, "wb_unavailable"},
+	{services.ErrUpstreamTimeout, http.StatusGatewayTimeout, "wb_timeout"},
+	{services.ErrUpstreamRejected, http.StatusBadGateway, "wb_rejected"},
+	{services.ErrDecode, http.StatusBadGateway, "wb_bad_response"},
+	{services.ErrGeneration, http.StatusInternalServerError, "generation_failed"},
+}
+
+// AbortWithError answers with an ErrorResponse and stops the handler chain.
+func AbortWithError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: message, Code: code, RequestID: logging.RequestID(c.Request.Context())})
+}
+
+// Errors answers requests whose handler attached an error with c.Error
+// instead of writing a response, choosing the status from the error kind.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+		err := last.Err
+		if errors.Is(err, services.ErrCancelled) {
+			c.AbortWithStatus(statusClientClosedRequest)
+			return
+		}
+
+		status, response := describe(err)
+		response.RequestID = logging.RequestID(c.Request.Context())
+		var serviceErr *services.Error
+		if errors.As(err, &serviceErr) && serviceErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(serviceErr.RetryAfter.Seconds()))))
+		}
+		c.AbortWithStatusJSON(status, response)
+	}
+}
+
+func describe(err error) (int, ErrorResponse) {
+	var scopeErr *wb.ScopeError
+	if errors.As(err, &scopeErr) {
+		return http.StatusUnprocessableEntity, ErrorResponse{Error: scopeErr.Error(), Code: "token_scope"}
+	}
+
+	for _, k := range errorKinds {
+		if !errors.Is(err, k.kind) {
+			continue
+		}
+		response := ErrorResponse{Error: err.Error(), Code: k.code}
+		var serviceErr *services.Error
+		if errors.As(err, &serviceErr) {
+			response.Error = serviceErr.Message()
+			response.WBRequestID = serviceErr.RequestID
+		}
+		return k.status, response
+	}
+	return http.StatusInternalServerError, ErrorResponse{Error: "Internal server error", Code: CodeInternal}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/logging"
+)
+
+// RequestIDHeader carries the request ID in both directions; a proxy in
+// front of the service may set it.
+const RequestIDHeader = "X-Request-Id"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID gives every request an ID, reusing a well-formed incoming one,
+// echoes it in the response and stores it in the request context for
+// logging.RequestID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog logs every request once it is answered, with the error the
+// handler attached if any. The log line is deferred so requests aborted with
+// http.ErrAbortHandler are logged too.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer logRequest(c, time.Now())
+		c.Next()
+	}
+}
+
+func logRequest(c *gin.Context, start time.Time) {
+	status := c.Writer.Status()
+	attrs := []slog.Attr{
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+		slog.Int("status", status),
+		slog.Duration("latency", time.Since(start)),
+		slog.Int("bytes", max(c.Writer.Size(), 0)),
+		slog.String("client_ip", c.ClientIP()),
+	}
+	if user, ok := CurrentUser(c); ok {
+		attrs = append(attrs, slog.String("user", user.Name))
+	}
+	if err := c.Errors.Last(); err != nil {
+		attrs = append(attrs, slog.Any("error", err.Err))
+	}
+
+	level := slog.LevelInfo
+	switch {
+	case status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(c.Request.Context(), level, "HTTP request", attrs...)
+}
+
+// Recovery answers 500 when a handler panics and logs the stack.
+// http.ErrAbortHandler is re-raised so net/http drops the connection, which
+// is how a streamed response reports failure after its headers went out.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			slog.ErrorContext(c.Request.Context(), "Panic serving request", "panic", r, "stack", string(debug.Stack()))
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			AbortWithError(c, http.StatusInternalServerError, CodeInternal, "Internal server error")
+		}()
+		c.Next()
+	}
+}
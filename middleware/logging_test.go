@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveAborted runs a handler that fails mid-stream the way the export
+// endpoint does, and returns what reached net/http.
+func serveAborted(router *gin.Engine) (recovered any) {
+	router.GET("/stream", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic(http.ErrAbortHandler)
+	})
+	defer func() { recovered = recover() }()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+	return nil
+}
+
+func TestAccessLogAbortedRequest(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AccessLog(), Recovery())
+	if r := serveAborted(router); r != http.ErrAbortHandler {
+		t.Fatalf("recovered %v, want http.ErrAbortHandler", r)
+	}
+
+	if line := buf.String(); !strings.Contains(line, `msg="HTTP request"`) || !strings.Contains(line, "path=/stream") {
+		t.Errorf("access log = %q", line)
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/metrics"
+)
+
+var httpDuration = metrics.NewHistogram("berrio_http_request_duration_seconds",
+	"Latency of API handlers by route and status.",
+	metrics.DefBuckets, "method", "route", "code")
+
+// Metrics records the latency of every request under its route pattern, so
+// seller and job IDs don't each become a series. Like AccessLog it observes
+// in a defer to count requests aborted with http.ErrAbortHandler.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer observeRequest(c, time.Now())
+		c.Next()
+	}
+}
+
+func observeRequest(c *gin.Context, start time.Time) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	httpDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+}
+
+// ScrapeToken requires "Authorization: Bearer <token>" on the metrics
+// endpoint; an empty token leaves it open.
+func ScrapeToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Invalid metrics token")
+			return
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/metrics"
+)
+
+func TestMetricsAbortedRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics(), Recovery())
+	if r := serveAborted(router); r != http.ErrAbortHandler {
+		t.Fatalf("recovered %v, want http.ErrAbortHandler", r)
+	}
+
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `berrio_http_request_duration_seconds_count{method="GET",route="/stream",code="200"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics lack %s", want)
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+type AnalyticOrderPeriod struct {
+	Begin string `json:"begin"`
+	End   string `json:"end"`
+}
+
+type AnalyticOrderBy struct {
+	Field string `json:"field"`
+	Mode  string `json:"mode"`
+}
+
+type AnalyticOrderRequest struct {
+	Timezone string              `json:"timezone"`
+	Period   AnalyticOrderPeriod `json:"period"`
+	OrderBy  AnalyticOrderBy     `json:"orderBy"`
+	Page     int                 `json:"page"`
+}
+
+type AnalyticOrderStatistics struct {
+	OrdersCount  int `json:"ordersCount"`
+	OrdersSumRub int `json:"ordersSumRub"`
+}
+
+type AnalyticOrderCard struct {
+	NmID       int    `json:"nmID"`
+	VendorCode string `json:"vendorCode"`
+	Statistics struct {
+		SelectedPeriod AnalyticOrderStatistics `json:"selectedPeriod"`
+		PreviousPeriod AnalyticOrderStatistics `json:"previousPeriod"`
+	} `json:"statistics"`
+}
+
+type AnalyticOrderResponse struct {
+	Data struct {
+		Page       int                 `json:"page"`
+		IsNextPage bool                `json:"isNextPage"`
+		Cards      []AnalyticOrderCard `json:"cards"`
+	} `json:"data"`
+}
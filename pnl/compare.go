@@ -0,0 +1,137 @@
+package pnl
+
+import (
+	"math"
+	"sort"
+)
+
+// Delta is a figure of the report period next to the period it is compared
+// with.
+type Delta struct {
+	Current  float64 `json:"current"`
+	Previous float64 `json:"previous"`
+	Change   float64 `json:"change"`
+	// ChangePct is Change in percent of the previous value; null when the
+	// previous value is zero.
+	ChangePct *float64 `json:"changePct"`
+}
+
+func newDelta(current, previous float64) Delta {
+	d := Delta{Current: current, Previous: previous, Change: round(current - previous)}
+	if previous != 0 {
+		pct := round(d.Change / math.Abs(previous) * 100)
+		d.ChangePct = &pct
+	}
+	return d
+}
+
+// FigureDelta is one Summary figure, named after its JSON field.
+type FigureDelta struct {
+	Figure string `json:"figure"`
+	Delta
+}
+
+// SKUDelta compares every figure of an SKURow.
+type SKUDelta struct {
+	SaName          string `json:"saName"`
+	TsName          string `json:"tsName,omitempty"`
+	UnitsSold       Delta  `json:"unitsSold"`
+	UnitsReturned   Delta  `json:"unitsReturned"`
+	Revenue         Delta  `json:"revenue"`
+	ForPay          Delta  `json:"forPay"`
+	Logistics       Delta  `json:"logistics"`
+	Storage         Delta  `json:"storage"`
+	Penalties       Delta  `json:"penalties"`
+	OtherDeductions Delta  `json:"otherDeductions"`
+	COGS            Delta  `json:"cogs"`
+	Tax             Delta  `json:"tax"`
+	NetProfit       Delta  `json:"netProfit"`
+}
+
+type Comparison struct {
+	// Mode is how the compared period was chosen, "previous" or "year".
+	Mode string `json:"mode"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Summary follows the order of the summary table.
+	Summary []FigureDelta `json:"summary"`
+	// SKUs covers every article of either period, biggest profit drop first.
+	SKUs []SKUDelta `json:"skus"`
+}
+
+// summaryFigures are the compared Summary fields in report order.
+var summaryFigures = []struct {
+	name  string
+	value func(Summary) float64
+}{
+	{"grossRevenue", func(s Summary) float64 { return s.GrossRevenue }},
+	{"netRevenue", func(s Summary) float64 { return s.NetRevenue }},
+	{"reductionInRevenue", func(s Summary) float64 { return s.ReductionInRevenue }},
+	{"logisticsExpenses", func(s Summary) float64 { return s.LogisticsExpenses }},
+	{"otherExpenses", func(s Summary) float64 { return s.OtherExpenses }},
+	{"revenueExcludingCOGS", func(s Summary) float64 { return s.RevenueExcludingCOGS }},
+	{"estimatedCOGS", func(s Summary) float64 { return s.EstimatedCOGS }},
+	{"revenueExcludingTaxes", func(s Summary) float64 { return s.RevenueExcludingTaxes }},
+	{"grossProfit", func(s Summary) float64 { return s.GrossProfit }},
+	{"tax", func(s Summary) float64 { return s.Tax }},
+	{"taxFinal", func(s Summary) float64 { return s.TaxFinal }},
+	{"netProfit", func(s Summary) float64 { return s.NetProfit }},
+}
+
+// Compare returns the change of every summary figure and SKU from previous
+// to current. Both are rounded first so the deltas match the Excel report.
+// The caller fills in the period.
+func Compare(current, previous Result) Comparison {
+	current, previous = current.Rounded(), previous.Rounded()
+
+	c := Comparison{Summary: make([]FigureDelta, 0, len(summaryFigures)), SKUs: []SKUDelta{}}
+	for _, f := range summaryFigures {
+		c.Summary = append(c.Summary, FigureDelta{
+			Figure: f.name,
+			Delta:  newDelta(f.value(current.Summary), f.value(previous.Summary)),
+		})
+	}
+
+	before := make(map[skuKey]SKURow, len(previous.SKUs))
+	for _, sku := range previous.SKUs {
+		before[skuKey{sku.SaName, sku.TsName}] = sku
+	}
+	skuDelta := func(cur, prev SKURow) SKUDelta {
+		return SKUDelta{
+			SaName:          cur.SaName,
+			TsName:          cur.TsName,
+			UnitsSold:       newDelta(float64(cur.UnitsSold), float64(prev.UnitsSold)),
+			UnitsReturned:   newDelta(float64(cur.UnitsReturned), float64(prev.UnitsReturned)),
+			Revenue:         newDelta(cur.Revenue, prev.Revenue),
+			ForPay:          newDelta(cur.ForPay, prev.ForPay),
+			Logistics:       newDelta(cur.Logistics, prev.Logistics),
+			Storage:         newDelta(cur.Storage, prev.Storage),
+			Penalties:       newDelta(cur.Penalties, prev.Penalties),
+			OtherDeductions: newDelta(cur.OtherDeductions, prev.OtherDeductions),
+			COGS:            newDelta(cur.COGS, prev.COGS),
+			Tax:             newDelta(cur.Tax, prev.Tax),
+			NetProfit:       newDelta(cur.NetProfit, prev.NetProfit),
+		}
+	}
+	for _, sku := range current.SKUs {
+		key := skuKey{sku.SaName, sku.TsName}
+		c.SKUs = append(c.SKUs, skuDelta(sku, before[key]))
+		delete(before, key)
+	}
+	// Articles that sold only in the previous period.
+	for key, sku := range before {
+		c.SKUs = append(c.SKUs, skuDelta(SKURow{SaName: key.saName, TsName: key.tsName}, sku))
+	}
+
+	sort.Slice(c.SKUs, func(i, j int) bool {
+		a, b := c.SKUs[i], c.SKUs[j]
+		if a.NetProfit.Change != b.NetProfit.Change {
+			return a.NetProfit.Change < b.NetProfit.Change
+		}
+		if a.SaName != b.SaName {
+			return a.SaName < b.SaName
+		}
+		return a.TsName < b.TsName
+	})
+	return c
+}
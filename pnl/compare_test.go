@@ -0,0 +1,51 @@
+package pnl
+
+import (
+	"testing"
+
+	"omnituan.online/models"
+)
+
+func TestCompareSKUs(t *testing.T) {
+	params := Params{Tax: 0.06, Discount: 2}
+	current := Compute([]models.ReportDetails{
+		sale("A", 1000, 800),
+		sale("A", 1000, 800),
+		logistics("A", 50, 0),
+		{SaName: "A", StorageFee: 10, Penalty: 5, Deduction: 3},
+	}, params)
+	previous := Compute([]models.ReportDetails{
+		sale("A", 1000, 800),
+		refund("A", 1000, 800),
+		sale("B", 400, 300),
+	}, params)
+
+	c := Compare(current, previous)
+	if len(c.SKUs) != 2 {
+		t.Fatalf("got %d SKUs, want A and B", len(c.SKUs))
+	}
+	// B sold only in the previous period, so its profit dropped the most.
+	if b := c.SKUs[0]; b.SaName != "B" || b.UnitsSold.Current != 0 || b.UnitsSold.Previous != 1 {
+		t.Errorf("first SKU = %+v, want B gone", b)
+	}
+
+	a := c.SKUs[1]
+	for _, tt := range []struct {
+		name            string
+		got             Delta
+		current, before float64
+	}{
+		{"unitsSold", a.UnitsSold, 2, 1},
+		{"unitsReturned", a.UnitsReturned, 0, 1},
+		{"logistics", a.Logistics, 50, 0},
+		{"storage", a.Storage, 10, 0},
+		{"penalties", a.Penalties, 5, 0},
+		{"otherDeductions", a.OtherDeductions, 3, 0},
+		{"cogs", a.COGS, 1000, 0},
+		{"tax", a.Tax, 96, 0},
+	} {
+		if tt.got.Current != tt.current || tt.got.Previous != tt.before || tt.got.Change != tt.current-tt.before {
+			t.Errorf("%s = %+v, want %v -> %v", tt.name, tt.got, tt.before, tt.current)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pnl
+
+import "omnituan.online/models"
+
+// CostPrice is the purchase cost of one unit of a SKU, identified by
+// barcode, WB article (NmID) or supplier article (SaName). The most specific
+// identifier present on a report row wins: barcode, then NmID, then SaName.
+type CostPrice struct {
+	SaName  string  `json:"saName,omitempty"`
+	Barcode string  `json:"barcode,omitempty"`
+	NmID    int64   `json:"nmId,omitempty"`
+	Cost    float64 `json:"cost" binding:"gte=0"`
+}
+
+type costTable struct {
+	bySaName  map[string]float64
+	byBarcode map[string]float64
+	byNmID    map[int64]float64
+}
+
+func newCostTable(prices []CostPrice) costTable {
+	t := costTable{
+		bySaName:  map[string]float64{},
+		byBarcode: map[string]float64{},
+		byNmID:    map[int64]float64{},
+	}
+	for _, p := range prices {
+		if p.Barcode != "" {
+			t.byBarcode[p.Barcode] = p.Cost
+		}
+		if p.NmID != 0 {
+			t.byNmID[p.NmID] = p.Cost
+		}
+		if p.SaName != "" {
+			t.bySaName[p.SaName] = p.Cost
+		}
+	}
+	return t
+}
+
+func (t costTable) lookup(r models.ReportDetails) (float64, bool) {
+	if cost, ok := t.byBarcode[r.Barcode]; ok && r.Barcode != "" {
+		return cost, true
+	}
+	if cost, ok := t.byNmID[r.NmID]; ok && r.NmID != 0 {
+		return cost, true
+	}
+	if cost, ok := t.bySaName[r.SaName]; ok && r.SaName != "" {
+		return cost, true
+	}
+	return 0, false
+}
+
+// unmatchedKey names a row in UnmatchedSKUs.
+func unmatchedKey(r models.ReportDetails) string {
+	if r.SaName != "" {
+		return r.SaName
+	}
+	return r.Barcode
+}
@@ -0,0 +1,235 @@
+// Package pnl computes the seller's profit and loss for a realization
+// report period. It is pure: rendering lives in services.
+package pnl
+
+import (
+	"math"
+	"sort"
+
+	"omnituan.online/models"
+)
+
+const (
+	docTypeSale   = "Продажа"
+	docTypeReturn = "Возврат"
+	operLogistics = "Логистика"
+)
+
+type Params struct {
+	// Tax is the share of revenue paid as tax, e.g. 0.06.
+	Tax float64
+	// Discount is the markup divisor used to estimate cost of goods from
+	// retail price, e.g. 3.5, for items missing from Costs.
+	Discount float64
+	Costs    []CostPrice
+	// GroupBySize splits the per-SKU breakdown by TsName.
+	GroupBySize bool
+}
+
+// SaleRow is one sold or returned item.
+type SaleRow struct {
+	SaName      string  `json:"saName"`
+	RetailPrice float64 `json:"retailPrice"`
+	ForPay      float64 `json:"forPay"`
+	COGS        float64 `json:"cogs"`
+	// Estimated is true when COGS came from the discount divisor rather
+	// than the cost table.
+	Estimated bool `json:"estimated"`
+}
+
+type LogisticsRow struct {
+	SaName      string  `json:"saName"`
+	DeliveryRub float64 `json:"deliveryRub"`
+}
+
+type OtherExpenses struct {
+	Fines       float64 `json:"fines"`
+	Storage     float64 `json:"storage"`
+	Advertising float64 `json:"advertising"`
+	Acceptance  float64 `json:"acceptance"`
+	Total       float64 `json:"total"`
+}
+
+type Summary struct {
+	TaxRate float64 `json:"taxRate"`
+
+	// GrossRevenue is the retail price of everything sold.
+	GrossRevenue float64 `json:"grossRevenue"`
+	// NetRevenue is what WB transfers for sales after its fees.
+	NetRevenue float64 `json:"netRevenue"`
+	// ReductionInRevenue is what WB claws back for returns.
+	ReductionInRevenue   float64 `json:"reductionInRevenue"`
+	LogisticsExpenses    float64 `json:"logisticsExpenses"`
+	OtherExpenses        float64 `json:"otherExpenses"`
+	RevenueExcludingCOGS float64 `json:"revenueExcludingCOGS"`
+	EstimatedCOGS        float64 `json:"estimatedCOGS"`
+	// RevenueExcludingTaxes is the retail price of returned items.
+	RevenueExcludingTaxes float64 `json:"revenueExcludingTaxes"`
+	GrossProfit           float64 `json:"grossProfit"`
+	// Tax is charged on retail revenue, TaxFinal on what WB paid out.
+	Tax       float64 `json:"tax"`
+	TaxFinal  float64 `json:"taxFinal"`
+	NetProfit float64 `json:"netProfit"`
+}
+
+type Result struct {
+	Sales     []SaleRow      `json:"sales"`
+	Returns   []SaleRow      `json:"returns"`
+	Logistics []LogisticsRow `json:"logistics"`
+	// CancelledLogistics is the subset of Logistics spent on orders the
+	// buyer cancelled or did not pick up.
+	CancelledLogistics []LogisticsRow `json:"cancelledLogistics"`
+	OtherExpenses      OtherExpenses  `json:"otherExpenses"`
+	Summary            Summary        `json:"summary"`
+	// SKUs is the per-article breakdown, least profitable first.
+	SKUs []SKURow `json:"skus"`
+	// UnmatchedSKUs lists sold or returned articles missing from a
+	// non-empty cost table.
+	UnmatchedSKUs []string `json:"unmatchedSkus"`
+	// Comparison is set when the report was compared with another period.
+	Comparison *Comparison `json:"comparison,omitempty"`
+}
+
+func Compute(reports []models.ReportDetails, params Params) Result {
+	result := Result{
+		Sales:              []SaleRow{},
+		Returns:            []SaleRow{},
+		Logistics:          []LogisticsRow{},
+		CancelledLogistics: []LogisticsRow{},
+		UnmatchedSKUs:      []string{},
+	}
+	summary := &result.Summary
+	other := &result.OtherExpenses
+	costs := newCostTable(params.Costs)
+	unmatched := map[string]bool{}
+	skus := newSKUAggregator(params.GroupBySize)
+
+	saleRow := func(r models.ReportDetails) SaleRow {
+		row := SaleRow{SaName: r.SaName, RetailPrice: r.RetailPrice, ForPay: r.PpvzForPay}
+		// Costs are per unit; older rows may carry quantity 0.
+		if cost, ok := costs.lookup(r); ok {
+			row.COGS = cost * float64(max(r.Quantity, 1))
+			return row
+		}
+		// The estimate follows GrossRevenue, which counts RetailPrice once
+		// per row whatever the quantity.
+		row.COGS = r.RetailPrice / params.Discount
+		row.Estimated = true
+		if len(params.Costs) > 0 {
+			unmatched[unmatchedKey(r)] = true
+		}
+		return row
+	}
+
+	for _, r := range reports {
+		if r.SaName != "" && r.DocTypeName == docTypeSale {
+			row := saleRow(r)
+			summary.GrossRevenue += r.RetailPrice
+			summary.NetRevenue += r.PpvzForPay
+			summary.EstimatedCOGS += row.COGS
+			result.Sales = append(result.Sales, row)
+
+			sku := skus.row(r)
+			sku.UnitsSold += r.Quantity
+			sku.Revenue += r.RetailPrice
+			sku.ForPay += r.PpvzForPay
+			sku.COGS += row.COGS
+		}
+
+		if r.DocTypeName == docTypeReturn {
+			row := saleRow(r)
+			summary.RevenueExcludingTaxes += r.RetailPrice
+			summary.ReductionInRevenue += r.PpvzForPay
+			summary.EstimatedCOGS -= row.COGS
+			result.Returns = append(result.Returns, row)
+
+			sku := skus.row(r)
+			sku.UnitsReturned += r.Quantity
+			sku.Revenue -= r.RetailPrice
+			sku.ForPay -= r.PpvzForPay
+			sku.COGS -= row.COGS
+		}
+
+		if r.SupplierOperName == operLogistics {
+			summary.LogisticsExpenses += r.DeliveryRub
+			skus.row(r).Logistics += r.DeliveryRub
+			row := LogisticsRow{SaName: r.SaName, DeliveryRub: r.DeliveryRub}
+			result.Logistics = append(result.Logistics, row)
+			if r.ReturnAmount == 1 {
+				result.CancelledLogistics = append(result.CancelledLogistics, row)
+			}
+		}
+
+		other.Fines += r.Penalty
+		other.Storage += r.StorageFee
+		other.Advertising += r.Deduction
+		other.Acceptance += r.Acceptance
+
+		if r.Penalty != 0 || r.StorageFee != 0 || r.Deduction != 0 || r.Acceptance != 0 {
+			sku := skus.row(r)
+			sku.Penalties += r.Penalty
+			sku.Storage += r.StorageFee
+			sku.OtherDeductions += r.Deduction + r.Acceptance
+		}
+	}
+	other.Total = other.Fines + other.Storage + other.Advertising + other.Acceptance
+
+	summary.TaxRate = params.Tax
+	summary.OtherExpenses = other.Total
+	summary.RevenueExcludingCOGS = summary.NetRevenue - summary.ReductionInRevenue - summary.LogisticsExpenses - summary.OtherExpenses
+	summary.GrossProfit = summary.RevenueExcludingCOGS - summary.EstimatedCOGS
+	summary.Tax = (summary.GrossRevenue - summary.RevenueExcludingTaxes) * params.Tax
+	summary.TaxFinal = (summary.NetRevenue - summary.ReductionInRevenue) * params.Tax
+	summary.NetProfit = summary.GrossProfit - summary.TaxFinal
+
+	result.SKUs = skus.result(params.Tax)
+
+	for sku := range unmatched {
+		result.UnmatchedSKUs = append(result.UnmatchedSKUs, sku)
+	}
+	sort.Strings(result.UnmatchedSKUs)
+
+	return result
+}
+
+// Rounded returns r with every aggregate rounded to kopecks, as shown in the
+// Excel report. Per-row values come from WB already rounded.
+func (r Result) Rounded() Result {
+	o := &r.OtherExpenses
+	o.Fines, o.Storage, o.Advertising, o.Acceptance, o.Total =
+		round(o.Fines), round(o.Storage), round(o.Advertising), round(o.Acceptance), round(o.Total)
+
+	s := &r.Summary
+	s.GrossRevenue = round(s.GrossRevenue)
+	s.NetRevenue = round(s.NetRevenue)
+	s.ReductionInRevenue = round(s.ReductionInRevenue)
+	s.LogisticsExpenses = round(s.LogisticsExpenses)
+	s.OtherExpenses = round(s.OtherExpenses)
+	s.RevenueExcludingCOGS = round(s.RevenueExcludingCOGS)
+	s.EstimatedCOGS = round(s.EstimatedCOGS)
+	s.RevenueExcludingTaxes = round(s.RevenueExcludingTaxes)
+	s.GrossProfit = round(s.GrossProfit)
+	s.Tax = round(s.Tax)
+	s.TaxFinal = round(s.TaxFinal)
+	s.NetProfit = round(s.NetProfit)
+
+	skus := make([]SKURow, len(r.SKUs))
+	for i, sku := range r.SKUs {
+		sku.Revenue = round(sku.Revenue)
+		sku.ForPay = round(sku.ForPay)
+		sku.Logistics = round(sku.Logistics)
+		sku.Storage = round(sku.Storage)
+		sku.Penalties = round(sku.Penalties)
+		sku.OtherDeductions = round(sku.OtherDeductions)
+		sku.COGS = round(sku.COGS)
+		sku.Tax = round(sku.Tax)
+		sku.NetProfit = round(sku.NetProfit)
+		skus[i] = sku
+	}
+	r.SKUs = skus
+	return r
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
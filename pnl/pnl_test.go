@@ -0,0 +1,233 @@
+package pnl
+
+import (
+	"testing"
+
+	"omnituan.online/models"
+)
+
+func sale(saName string, retailPrice, forPay float64) models.ReportDetails {
+	return models.ReportDetails{SaName: saName, DocTypeName: docTypeSale, Quantity: 1, RetailPrice: retailPrice, PpvzForPay: forPay}
+}
+
+func refund(saName string, retailPrice, forPay float64) models.ReportDetails {
+	return models.ReportDetails{SaName: saName, DocTypeName: docTypeReturn, Quantity: 1, RetailPrice: retailPrice, PpvzForPay: forPay}
+}
+
+func logistics(saName string, deliveryRub float64, returnAmount int) models.ReportDetails {
+	return models.ReportDetails{SaName: saName, SupplierOperName: operLogistics, DeliveryRub: deliveryRub, ReturnAmount: returnAmount}
+}
+
+// mixedReports has a bit of everything; its summary is checked both by hand
+// and against the formulas of the original Excel report.
+var mixedReports = []models.ReportDetails{
+	sale("A", 1000, 800),
+	sale("B", 500, 400),
+	refund("A", 1000, 800),
+	logistics("A", 50, 0),
+	logistics("A", 60, 1),
+	{StorageFee: 30, Penalty: 20},
+}
+
+func TestCompute(t *testing.T) {
+	params := Params{Tax: 0.06, Discount: 2}
+
+	tests := []struct {
+		name    string
+		reports []models.ReportDetails
+		want    Summary
+		check   func(t *testing.T, r Result)
+	}{
+		{
+			name: "sales",
+			reports: []models.ReportDetails{
+				sale("A", 1000, 800),
+				sale("B", 500, 400),
+				sale("", 300, 200), // no article: not a sale of ours
+			},
+			want: Summary{
+				TaxRate:              0.06,
+				GrossRevenue:         1500,
+				NetRevenue:           1200,
+				RevenueExcludingCOGS: 1200,
+				EstimatedCOGS:        750,
+				GrossProfit:          450,
+				Tax:                  90,
+				TaxFinal:             72,
+				NetProfit:            378,
+			},
+			check: func(t *testing.T, r Result) {
+				if len(r.Sales) != 2 || !r.Sales[0].Estimated || r.Sales[0].COGS != 500 {
+					t.Errorf("sales = %+v", r.Sales)
+				}
+			},
+		},
+		{
+			name:    "returns",
+			reports: []models.ReportDetails{sale("A", 1000, 800), refund("A", 1000, 800)},
+			want: Summary{
+				TaxRate:               0.06,
+				GrossRevenue:          1000,
+				NetRevenue:            800,
+				ReductionInRevenue:    800,
+				RevenueExcludingTaxes: 1000,
+			},
+			check: func(t *testing.T, r Result) {
+				if len(r.Returns) != 1 || r.Returns[0].ForPay != 800 {
+					t.Errorf("returns = %+v", r.Returns)
+				}
+				if len(r.SKUs) != 1 || r.SKUs[0].UnitsSold != 1 || r.SKUs[0].UnitsReturned != 1 || r.SKUs[0].Revenue != 0 {
+					t.Errorf("skus = %+v", r.SKUs)
+				}
+			},
+		},
+		{
+			name:    "logistics",
+			reports: []models.ReportDetails{logistics("A", 50, 0), logistics("B", 70, 0)},
+			want: Summary{
+				TaxRate:              0.06,
+				LogisticsExpenses:    120,
+				RevenueExcludingCOGS: -120,
+				GrossProfit:          -120,
+				NetProfit:            -120,
+			},
+			check: func(t *testing.T, r Result) {
+				if len(r.Logistics) != 2 || len(r.CancelledLogistics) != 0 {
+					t.Errorf("logistics = %+v, cancelled = %+v", r.Logistics, r.CancelledLogistics)
+				}
+			},
+		},
+		{
+			name:    "cancelled logistics",
+			reports: []models.ReportDetails{logistics("A", 50, 1), logistics("B", 70, 0)},
+			want: Summary{
+				TaxRate:              0.06,
+				LogisticsExpenses:    120,
+				RevenueExcludingCOGS: -120,
+				GrossProfit:          -120,
+				NetProfit:            -120,
+			},
+			check: func(t *testing.T, r Result) {
+				want := LogisticsRow{SaName: "A", DeliveryRub: 50}
+				if len(r.Logistics) != 2 || len(r.CancelledLogistics) != 1 || r.CancelledLogistics[0] != want {
+					t.Errorf("logistics = %+v, cancelled = %+v", r.Logistics, r.CancelledLogistics)
+				}
+			},
+		},
+		{
+			name: "storage and penalties",
+			reports: []models.ReportDetails{
+				{Penalty: 100, StorageFee: 30.5},
+				{SaName: "A", Deduction: 200, Acceptance: 10},
+			},
+			want: Summary{
+				TaxRate:              0.06,
+				OtherExpenses:        340.5,
+				RevenueExcludingCOGS: -340.5,
+				GrossProfit:          -340.5,
+				NetProfit:            -340.5,
+			},
+			check: func(t *testing.T, r Result) {
+				want := OtherExpenses{Fines: 100, Storage: 30.5, Advertising: 200, Acceptance: 10, Total: 340.5}
+				if r.OtherExpenses != want {
+					t.Errorf("other expenses = %+v, want %+v", r.OtherExpenses, want)
+				}
+			},
+		},
+		{
+			name:    "summary totals",
+			reports: mixedReports,
+			want: Summary{
+				TaxRate:               0.06,
+				GrossRevenue:          1500,
+				NetRevenue:            1200,
+				ReductionInRevenue:    800,
+				LogisticsExpenses:     110,
+				OtherExpenses:         50,
+				RevenueExcludingCOGS:  240,
+				EstimatedCOGS:         250,
+				RevenueExcludingTaxes: 1000,
+				GrossProfit:           -10,
+				Tax:                   30,
+				TaxFinal:              24,
+				NetProfit:             -34,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Compute(tt.reports, params).Rounded()
+			if r.Summary != tt.want {
+				t.Errorf("summary =\n%+v\nwant\n%+v", r.Summary, tt.want)
+			}
+			if tt.check != nil {
+				tt.check(t, r)
+			}
+		})
+	}
+}
+
+// legacySummary is the summary as the Excel report computed it before the
+// pnl package existed, with COGS estimated from the net retail revenue.
+func legacySummary(reports []models.ReportDetails, taxPt, discountPt float64) Summary {
+	var s Summary
+	for _, r := range reports {
+		if r.SaName != "" && r.DocTypeName == "Продажа" {
+			s.GrossRevenue += r.RetailPrice
+			s.NetRevenue += r.PpvzForPay
+		}
+		if r.DocTypeName == "Возврат" {
+			s.RevenueExcludingTaxes += r.RetailPrice
+			s.ReductionInRevenue += r.PpvzForPay
+		}
+		if r.SupplierOperName == "Логистика" {
+			s.LogisticsExpenses += r.DeliveryRub
+		}
+		s.OtherExpenses += r.Penalty + r.StorageFee + r.Deduction + r.Acceptance
+	}
+	s.TaxRate = taxPt
+	s.RevenueExcludingCOGS = s.NetRevenue - s.ReductionInRevenue - s.LogisticsExpenses - s.OtherExpenses
+	s.EstimatedCOGS = (s.GrossRevenue - s.RevenueExcludingTaxes) / discountPt
+	s.GrossProfit = s.RevenueExcludingCOGS - s.EstimatedCOGS
+	s.Tax = (s.GrossRevenue - s.RevenueExcludingTaxes) * taxPt
+	s.TaxFinal = (s.NetRevenue - s.ReductionInRevenue) * taxPt
+	s.NetProfit = s.GrossProfit - s.TaxFinal
+	return s
+}
+
+func TestComputeCOGSPerUnit(t *testing.T) {
+	a, b := sale("A", 300, 250), sale("B", 300, 250)
+	a.Quantity, b.Quantity = 3, 2
+	r := Compute([]models.ReportDetails{a, b}, Params{Tax: 0.06, Discount: 2, Costs: []CostPrice{{SaName: "B", Cost: 100}}})
+
+	// B is in the cost table, which is per unit. A falls back to the
+	// discount divisor, which like GrossRevenue counts the row once.
+	if r.Sales[0].COGS != 150 || !r.Sales[0].Estimated {
+		t.Errorf("A = %+v, want estimated COGS 150", r.Sales[0])
+	}
+	if r.Sales[1].COGS != 200 || r.Sales[1].Estimated {
+		t.Errorf("B = %+v, want COGS 200 from the cost table", r.Sales[1])
+	}
+	if r.Summary.EstimatedCOGS != 350 {
+		t.Errorf("EstimatedCOGS = %v, want 350", r.Summary.EstimatedCOGS)
+	}
+}
+
+func TestComputeMatchesLegacyReport(t *testing.T) {
+	multi := append([]models.ReportDetails{}, mixedReports...)
+	multi[0].Quantity, multi[1].Quantity, multi[2].Quantity = 3, 2, 2
+
+	for name, reports := range map[string][]models.ReportDetails{
+		"one unit per row":    mixedReports,
+		"several units a row": multi,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := Compute(reports, Params{Tax: 0.07, Discount: 3.5}).Rounded().Summary
+			want := Result{Summary: legacySummary(reports, 0.07, 3.5)}.Rounded().Summary
+			if got != want {
+				t.Errorf("summary =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
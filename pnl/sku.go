@@ -0,0 +1,74 @@
+package pnl
+
+import (
+	"sort"
+
+	"omnituan.online/models"
+)
+
+// SKURow is the profit and loss of one supplier article (and size, when
+// Params.GroupBySize is set). Rows with an empty SaName collect charges WB
+// does not attribute to an article, such as storage.
+type SKURow struct {
+	SaName        string `json:"saName"`
+	TsName        string `json:"tsName,omitempty"`
+	UnitsSold     int    `json:"unitsSold"`
+	UnitsReturned int    `json:"unitsReturned"`
+	// Revenue and ForPay are net of returns.
+	Revenue         float64 `json:"revenue"`
+	ForPay          float64 `json:"forPay"`
+	Logistics       float64 `json:"logistics"`
+	Storage         float64 `json:"storage"`
+	Penalties       float64 `json:"penalties"`
+	OtherDeductions float64 `json:"otherDeductions"`
+	COGS            float64 `json:"cogs"`
+	Tax             float64 `json:"tax"`
+	NetProfit       float64 `json:"netProfit"`
+}
+
+type skuKey struct {
+	saName string
+	tsName string
+}
+
+type skuAggregator struct {
+	bySize bool
+	rows   map[skuKey]*SKURow
+}
+
+func newSKUAggregator(bySize bool) *skuAggregator {
+	return &skuAggregator{bySize: bySize, rows: map[skuKey]*SKURow{}}
+}
+
+func (a *skuAggregator) row(r models.ReportDetails) *SKURow {
+	key := skuKey{saName: r.SaName}
+	if a.bySize {
+		key.tsName = r.TsName
+	}
+	row, ok := a.rows[key]
+	if !ok {
+		row = &SKURow{SaName: key.saName, TsName: key.tsName}
+		a.rows[key] = row
+	}
+	return row
+}
+
+// result finishes the per-SKU figures and returns them least profitable first.
+func (a *skuAggregator) result(taxRate float64) []SKURow {
+	rows := make([]SKURow, 0, len(a.rows))
+	for _, row := range a.rows {
+		row.Tax = row.ForPay * taxRate
+		row.NetProfit = row.ForPay - row.Logistics - row.Storage - row.Penalties - row.OtherDeductions - row.COGS - row.Tax
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].NetProfit != rows[j].NetProfit {
+			return rows[i].NetProfit < rows[j].NetProfit
+		}
+		if rows[i].SaName != rows[j].SaName {
+			return rows[i].SaName < rows[j].SaName
+		}
+		return rows[i].TsName < rows[j].TsName
+	})
+	return rows
+}
@@ -0,0 +1,76 @@
+// Package server wires the HTTP routes of the report service.
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/auth"
+	"omnituan.online/controllers"
+	"omnituan.online/metrics"
+	"omnituan.online/middleware"
+
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	_ "omnituan.online/docs"
+)
+
+// Options are the dependencies of the router itself; report jobs, the
+// seller vault and auth must also be installed with controllers.SetReportJobs,
+// controllers.SetSellerVault and controllers.SetAuth.
+type Options struct {
+	Issuer *auth.Issuer
+	Users  *auth.Users
+	// CORSOrigins are the browser origins allowed to call the API.
+	CORSOrigins []string
+	// Metrics serves /metrics, guarded by MetricsToken when it is set.
+	Metrics      bool
+	MetricsToken string
+}
+
+// NewRouter returns the gin engine serving the /api/v1 endpoints, the
+// health probes, the Swagger UI and, if enabled, the Prometheus metrics.
+// Everything under /api/v1 except /auth/token needs a bearer token.
+func NewRouter(opts Options) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Metrics(), middleware.Recovery())
+	router.Use(middleware.CORS(opts.CORSOrigins), middleware.Errors())
+
+	router.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Welcome"})
+	})
+	router.GET("/healthz", controllers.Healthz)
+	router.GET("/readyz", controllers.Readyz)
+
+	v1 := router.Group("/api/v1")
+	v1.POST("/auth/token", controllers.IssueToken)
+
+	api := v1.Group("", middleware.Authenticate(opts.Issuer, opts.Users))
+	{
+		api.POST("/reports", controllers.HandleReportRequest)
+		api.POST("/reports/summary", controllers.GetReportSummary)
+		api.POST("/reports/export", controllers.ExportReportDetails)
+		api.GET("/reports/:id", controllers.GetReportJob)
+		api.GET("/reports/:id/download", controllers.DownloadReportJob)
+		api.POST("/orders", controllers.GetOrdersReport)
+
+		api.GET("/sellers", controllers.ListSellers)
+		api.GET("/sellers/:id", controllers.GetSeller)
+		api.GET("/sellers/:id/token", controllers.InspectSellerToken)
+		api.POST("/sellers/:id/validate", controllers.ValidateSellerToken)
+	}
+
+	admin := api.Group("", middleware.RequireAdmin())
+	{
+		admin.POST("/sellers", controllers.CreateSeller)
+		admin.PUT("/sellers/:id/token", controllers.RotateSellerToken)
+		admin.DELETE("/sellers/:id", controllers.DeleteSeller)
+	}
+
+	if opts.Metrics {
+		router.GET("/metrics", middleware.ScrapeToken(opts.MetricsToken), gin.WrapH(metrics.Handler()))
+	}
+
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	return router
+}
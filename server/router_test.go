@@ -0,0 +1,251 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"omnituan.online/auth"
+	"omnituan.online/controllers"
+	"omnituan.online/jobs"
+	"omnituan.online/middleware"
+	"omnituan.online/services"
+	"omnituan.online/vault"
+	"omnituan.online/wb"
+	"omnituan.online/wb/wbfake"
+)
+
+// testAPI is the router wired to wbfake with one admin user.
+type testAPI struct {
+	t       *testing.T
+	handler http.Handler
+	vault   *vault.Vault
+	bearer  string
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+
+	fake := wbfake.NewServer()
+	t.Cleanup(fake.Close)
+	services.SetWBClient(wb.NewClient(fake.Config()))
+
+	sellers, err := vault.Open(filepath.Join(dir, "sellers.json"), bytes.Repeat([]byte{1}, vault.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	controllers.SetSellerVault(sellers)
+
+	issuer, err := auth.NewIssuer(bytes.Repeat([]byte{2}, auth.MinSecretSize), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := auth.OpenUsers(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Put(auth.User{Name: "root", Admin: true}); err != nil {
+		t.Fatal(err)
+	}
+	controllers.SetAuth(issuer, users)
+	bearer, _, err := issuer.Issue("root", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportJobs := jobs.NewManager(1, 4, time.Hour)
+	t.Cleanup(reportJobs.Close)
+	controllers.SetReportJobs(reportJobs)
+
+	return &testAPI{
+		t:       t,
+		handler: NewRouter(Options{Issuer: issuer, Users: users, CORSOrigins: []string{"http://localhost:3000"}}),
+		vault:   sellers,
+		bearer:  bearer,
+	}
+}
+
+// seller registers a seller whose WB token has the given ID and scopes.
+func (a *testAPI) seller(tokenID string, scopes wb.Scope) string {
+	a.t.Helper()
+	s, err := a.vault.Add(tokenID, wbfake.Token(tokenID, scopes, time.Now().Add(24*time.Hour)))
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return s.ID
+}
+
+func (a *testAPI) do(method, path string, body any) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Authorization", "Bearer "+a.bearer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	a.handler.ServeHTTP(w, req)
+	return w
+}
+
+// submit posts a report request and waits for the job to finish.
+func (a *testAPI) submit(sellerID string) jobs.Snapshot {
+	a.t.Helper()
+	w := a.do(http.MethodPost, "/api/v1/reports", map[string]any{
+		"sellerId": sellerID,
+		"dateFrom": "2025-05-05",
+		"dateTo":   "2025-05-25",
+	})
+	if w.Code != http.StatusAccepted {
+		a.t.Fatalf("POST /reports = %d %s", w.Code, w.Body)
+	}
+	location := w.Header().Get("Location")
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job jobs.Snapshot
+		w := a.do(http.MethodGet, location, nil)
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil || w.Code != http.StatusOK {
+			a.t.Fatalf("GET %s = %d %s", location, w.Code, w.Body)
+		}
+		if job.Status == jobs.StatusDone || job.Status == jobs.StatusFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			a.t.Fatalf("job %s still %s", job.ID, job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var res middleware.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("error body %q: %v", w.Body, err)
+	}
+	return res.Code
+}
+
+func TestReportDownload(t *testing.T) {
+	api := newTestAPI(t)
+	job := api.submit(api.seller("demo", wb.ScopeStatistics))
+	if job.Status != jobs.StatusDone {
+		t.Fatalf("job %s: %s", job.Status, job.Error)
+	}
+	if job.PagesFetched == 0 || job.Rows == 0 {
+		t.Errorf("job fetched %d pages, %d rows", job.PagesFetched, job.Rows)
+	}
+
+	w := api.do(http.MethodGet, "/api/v1/reports/"+job.ID+"/download", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("download = %d %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "report_total.xlsx" {
+		var names []string
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		t.Errorf("archive holds %s, want report_total.xlsx", strings.Join(names, ", "))
+	}
+}
+
+func TestReportRevokedToken(t *testing.T) {
+	api := newTestAPI(t)
+	// The token looks valid locally, so only WB's 401 fails the job.
+	job := api.submit(api.seller(wbfake.RevokedTokenID, wb.ScopeStatistics))
+	if job.Status != jobs.StatusFailed {
+		t.Fatalf("job %s, want failed", job.Status)
+	}
+
+	w := api.do(http.MethodGet, "/api/v1/reports/"+job.ID+"/download", nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("download = %d %s", w.Code, w.Body)
+	}
+	if code := errorCode(t, w); code != "wb_unauthorized" {
+		t.Errorf("code = %q, want wb_unauthorized", code)
+	}
+}
+
+func TestReportMissingScope(t *testing.T) {
+	api := newTestAPI(t)
+	w := api.do(http.MethodPost, "/api/v1/reports", map[string]any{
+		"sellerId": api.seller("analytics-only", wb.ScopeAnalytics),
+		"dateFrom": "2025-05-05",
+		"dateTo":   "2025-05-25",
+	})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("POST /reports = %d %s", w.Code, w.Body)
+	}
+	if code := errorCode(t, w); code != "token_scope" {
+		t.Errorf("code = %q, want token_scope", code)
+	}
+}
+
+func TestReportSummaryTax(t *testing.T) {
+	api := newTestAPI(t)
+	sellerID := api.seller("demo", wb.ScopeStatistics)
+
+	tests := []struct {
+		name     string
+		fields   map[string]any
+		status   int
+		wantRate float64
+	}{
+		{"omitted uses the config", nil, http.StatusOK, 0.06},
+		{"zero is kept", map[string]any{"tax": 0}, http.StatusOK, 0},
+		{"explicit", map[string]any{"tax": 0.15}, http.StatusOK, 0.15},
+		{"negative tax", map[string]any{"tax": -0.1}, http.StatusBadRequest, 0},
+		{"tax of 100%", map[string]any{"tax": 1}, http.StatusBadRequest, 0},
+		{"zero discount", map[string]any{"discount": 0}, http.StatusBadRequest, 0},
+		{"negative discount", map[string]any{"discount": -2}, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{"sellerId": sellerID, "dateFrom": "2025-05-05", "dateTo": "2025-05-25"}
+			for k, v := range tt.fields {
+				body[k] = v
+			}
+			w := api.do(http.MethodPost, "/api/v1/reports/summary", body)
+			if w.Code != tt.status {
+				t.Fatalf("POST /reports/summary = %d %s", w.Code, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var res struct {
+				Summary struct {
+					TaxRate float64 `json:"taxRate"`
+				} `json:"summary"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Summary.TaxRate != tt.wantRate {
+				t.Errorf("taxRate = %v, want %v", res.Summary.TaxRate, tt.wantRate)
+			}
+		})
+	}
+}
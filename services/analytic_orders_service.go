@@ -1,47 +1,21 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"log/slog"
 	"time"
+
+	"omnituan.online/models"
+	"omnituan.online/wb"
 )
 
-type AnalyticOrderRequest struct {
-	Timezone string `json:"timezone"`
-	Period   struct {
-		Begin string `json:"begin"`
-		End   string `json:"end"`
-	} `json:"period"`
-	OrderBy struct {
-		Field string `json:"field"`
-		Mode  string `json:"mode"`
-	} `json:"orderBy"`
-	Page int `json:"page"`
-}
+// OrdersPageInterval spaces nm-report/detail calls: WB allows 3 requests per
+// minute for this endpoint.
+var OrdersPageInterval = 20 * time.Second
 
-type AnalyticOrderResponse struct {
-	Data struct {
-		Page       int  `json:"page"`
-		IsNextPage bool `json:"isNextPage"`
-		Cards      []struct {
-			NmID       int    `json:"nmID"`
-			VendorCode string `json:"vendorCode"`
-			Statistics struct {
-				SelectedPeriod struct {
-					OrdersCount  int `json:"ordersCount"`
-					OrdersSumRub int `json:"ordersSumRub"`
-				} `json:"selectedPeriod"`
-				PreviousPeriod struct {
-					OrdersCount  int `json:"ordersCount"`
-					OrdersSumRub int `json:"ordersSumRub"`
-				} `json:"previousPeriod"`
-			} `json:"statistics"`
-		} `json:"cards"`
-	} `json:"data"`
-}
+// OrdersTimezone is the zone WB reads the orders period in.
+var OrdersTimezone = "Europe/Moscow"
 
 type ChartData struct {
 	NmID             int    `json:"nmID"`
@@ -56,67 +30,53 @@ type OrdersResponse struct {
 	ChartData       []ChartData `json:"chartData"`
 	TotalOrders     int         `json:"totalOrders"`
 	TotalPrevOrders int         `json:"totalPrevOrders"`
+	Pages           int         `json:"pages"`
 }
 
-func GetOrders(apiKey, begin, end string) (OrdersResponse, error) {
-	payload := AnalyticOrderRequest{
-		Timezone: "Europe/Moscow",
-		Period: struct {
-			Begin string `json:"begin"`
-			End   string `json:"end"`
-		}{
+func GetOrders(ctx context.Context, apiKey, begin, end string) (OrdersResponse, error) {
+	if err := checkOrdersPeriod(begin, end); err != nil {
+		return OrdersResponse{}, err
+	}
+	if err := CheckToken(apiKey, wb.ScopeAnalytics); err != nil {
+		return OrdersResponse{}, err
+	}
+
+	payload := models.AnalyticOrderRequest{
+		Timezone: OrdersTimezone,
+		Period: models.AnalyticOrderPeriod{
 			Begin: begin,
 			End:   end,
 		},
-		OrderBy: struct {
-			Field string `json:"field"`
-			Mode  string `json:"mode"`
-		}{
+		OrderBy: models.AnalyticOrderBy{
 			Field: "orders",
 			Mode:  "desc",
 		},
 		Page: 1,
 	}
 
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Println(err)
-		return OrdersResponse{}, err
-	}
-
-	req, err := http.NewRequest("POST", "https://seller-analytics-api.wildberries.ru/api/v2/nm-report/detail", bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		fmt.Println(err)
-		return OrdersResponse{}, err
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println(err)
-		return OrdersResponse{}, err
-	}
-	defer resp.Body.Close()
+	var cards []models.AnalyticOrderCard
+	for {
+		analyticOrderResponse, err := wbClient.NMReportDetail(ctx, apiKey, payload)
+		if err != nil {
+			return OrdersResponse{}, upstreamError(ctx, err)
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println(err)
-		return OrdersResponse{}, err
-	}
+		cards = append(cards, analyticOrderResponse.Data.Cards...)
+		slog.DebugContext(ctx, "Fetched orders page", "page", payload.Page, "cards", len(analyticOrderResponse.Data.Cards))
 
-	var analyticOrderResponse AnalyticOrderResponse
-	if err := json.Unmarshal(body, &analyticOrderResponse); err != nil {
-		fmt.Println(err)
-		return OrdersResponse{}, err
+		if !analyticOrderResponse.Data.IsNextPage {
+			break
+		}
+		payload.Page++
+		if err := wb.Sleep(ctx, OrdersPageInterval); err != nil {
+			return OrdersResponse{}, checkContext(ctx)
+		}
 	}
 
 	var chartData []ChartData
 	var totalCountOrders int
 	var totalPrevCountOders int
-	for _, card := range analyticOrderResponse.Data.Cards {
+	for _, card := range cards {
 		totalCountOrders += card.Statistics.SelectedPeriod.OrdersCount
 		totalPrevCountOders += card.Statistics.PreviousPeriod.OrdersCount
 		ordersCount := card.Statistics.SelectedPeriod.OrdersCount
@@ -138,5 +98,31 @@ func GetOrders(apiKey, begin, end string) (OrdersResponse, error) {
 		ChartData:       chartData,
 		TotalOrders:     totalCountOrders,
 		TotalPrevOrders: totalPrevCountOders,
+		Pages:           payload.Page,
 	}, nil
 }
+
+// checkOrdersPeriod accepts the date or date and time forms WB takes for
+// the nm-report period.
+func checkOrdersPeriod(begin, end string) error {
+	parse := func(name, value string) (time.Time, error) {
+		for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, &Error{Kind: ErrInvalidPeriod, Detail: fmt.Sprintf("%s %q is not YYYY-MM-DD or YYYY-MM-DD HH:MM:SS", name, value)}
+	}
+	from, err := parse("start", begin)
+	if err != nil {
+		return err
+	}
+	to, err := parse("end", end)
+	if err != nil {
+		return err
+	}
+	if to.Before(from) {
+		return &Error{Kind: ErrInvalidPeriod, Detail: "end is before start"}
+	}
+	return nil
+}
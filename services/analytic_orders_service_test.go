@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"omnituan.online/wb"
+	"omnituan.online/wb/wbfake"
+)
+
+func TestGetOrdersMergesPages(t *testing.T) {
+	fake := wbfake.NewServer()
+	defer fake.Close()
+	fake.CardsPerPage = 3
+
+	var calls atomic.Int32
+	next := fake.Server.Config.Handler
+	fake.Server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/nm-report/detail" {
+			calls.Add(1)
+		}
+		next.ServeHTTP(w, r)
+	})
+
+	defer SetWBClient(wbClient)
+	SetWBClient(wb.NewClient(fake.Config()))
+	defer func(d time.Duration) { OrdersPageInterval = d }(OrdersPageInterval)
+	OrdersPageInterval = 0
+
+	token := wbfake.Token("orders-test", wb.ScopeAnalytics, time.Now().Add(time.Hour))
+	res, err := GetOrders(context.Background(), token, "2025-05-05", "2025-05-25")
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+
+	// 8 cards at 3 per page.
+	if res.Pages != 3 || calls.Load() != 3 {
+		t.Errorf("pages = %d, requests = %d, want 3 and 3", res.Pages, calls.Load())
+	}
+	if res.TotalOrders != 295 || res.TotalPrevOrders != 199 {
+		t.Errorf("totals = %d/%d, want 295/199", res.TotalOrders, res.TotalPrevOrders)
+	}
+
+	// BR-BELT-08 has no orders in either period and is left out.
+	want := []string{
+		"BR-TSHIRT-01", "BR-HOODIE-02", "BR-CAP-03", "BR-SOCKS-04",
+		"BR-JEANS-05", "BR-SCARF-06", "BR-BAG-07",
+	}
+	if len(res.ChartData) != len(want) {
+		t.Fatalf("got %d cards, want %d", len(res.ChartData), len(want))
+	}
+	for i, card := range res.ChartData {
+		if card.VendorCode != want[i] {
+			t.Errorf("card %d = %s, want %s", i, card.VendorCode, want[i])
+		}
+	}
+	if bag := res.ChartData[6]; bag.OrdersCount != 0 || bag.PrevOrdersCount != 6 {
+		t.Errorf("BR-BAG-07 orders = %d/%d, want 0/6", bag.OrdersCount, bag.PrevOrdersCount)
+	}
+}
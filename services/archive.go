@@ -0,0 +1,121 @@
+package services
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"omnituan.online/i18n"
+	"omnituan.online/models"
+	"omnituan.online/pnl"
+)
+
+// Report outputs that can be requested in the archive.
+const (
+	OutputTotal    = "total"
+	OutputDetailed = "detailed"
+)
+
+type ArchiveOptions struct {
+	Params pnl.Params
+	// Outputs lists the workbooks to include; empty means OutputTotal only.
+	Outputs []string
+	Locale  i18n.Locale
+	// Baseline adds a comparison sheet to the total report.
+	Baseline *Baseline
+}
+
+// WriteReportArchive renders the requested report workbooks straight into
+// a zip written to w.
+func WriteReportArchive(w io.Writer, reports []models.ReportDetails, opts ArchiveOptions) error {
+	zipWriter := zip.NewWriter(w)
+
+	for _, file := range reportFiles(reports, opts) {
+		fw, err := zipWriter.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("failed to create zip entry %s: %w", file.name, err)
+		}
+		if err := file.write(fw); err != nil {
+			return &Error{Kind: ErrGeneration, Detail: file.name, Err: err}
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to close zip: %w", err)
+	}
+	return nil
+}
+
+// WriteReportFiles renders the requested report workbooks as separate files
+// in dir and returns their paths.
+func WriteReportFiles(dir string, reports []models.ReportDetails, opts ArchiveOptions) ([]string, error) {
+	var paths []string
+	for _, file := range reportFiles(reports, opts) {
+		path := filepath.Join(dir, file.name)
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, err
+		}
+		err = file.write(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, &Error{Kind: ErrGeneration, Detail: path, Err: err}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+type reportFile struct {
+	name string
+	// output is the OutputTotal or OutputDetailed the file renders.
+	output string
+	render func(w io.Writer) error
+}
+
+// write renders the file into w and records how long it took and how big
+// it came out.
+func (f reportFile) write(w io.Writer) error {
+	start := time.Now()
+	counter := &countingWriter{w: w}
+	if err := f.render(counter); err != nil {
+		return err
+	}
+	excelDuration.Observe(time.Since(start).Seconds(), f.output)
+	excelSize.Observe(float64(counter.n), f.output)
+	return nil
+}
+
+func reportFiles(reports []models.ReportDetails, opts ArchiveOptions) []reportFile {
+	outputs := opts.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{OutputTotal}
+	}
+
+	var files []reportFile
+	if slices.Contains(outputs, OutputDetailed) {
+		files = append(files, reportFile{
+			name:   fmt.Sprintf("report_%s.xlsx", opts.Locale),
+			output: OutputDetailed,
+			render: func(w io.Writer) error {
+				return GenerateDetailedExcel(w, reports, opts.Locale)
+			},
+		})
+	}
+	if slices.Contains(outputs, OutputTotal) {
+		files = append(files, reportFile{
+			name:   "report_total.xlsx",
+			output: OutputTotal,
+			render: func(w io.Writer) error {
+				return GenerateReportExcel(w, ComputeReport(reports, opts.Params, opts.Baseline), opts.Locale)
+			},
+		})
+	}
+	return files
+}
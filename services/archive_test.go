@@ -0,0 +1,76 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"omnituan.online/i18n"
+	"omnituan.online/models"
+	"omnituan.online/pnl"
+	"omnituan.online/wb/wbfake"
+)
+
+// benchmarkRows is roughly a month of a busy seller's realization report.
+const benchmarkRows = 50000
+
+func benchmarkArchive(b *testing.B) ([]models.ReportDetails, ArchiveOptions) {
+	b.Helper()
+	fake := wbfake.NewServer()
+	fixture := fake.Reports
+	fake.Close()
+
+	reports := make([]models.ReportDetails, benchmarkRows)
+	for i := range reports {
+		reports[i] = fixture[i%len(fixture)]
+		reports[i].RrdID = int64(i + 1)
+	}
+	return reports, ArchiveOptions{
+		Params:  pnl.Params{Tax: 0.06, Discount: 3.5},
+		Outputs: []string{OutputTotal, OutputDetailed},
+		Locale:  i18n.Default,
+	}
+}
+
+// BenchmarkWriteReportArchive streams both workbooks into the zip.
+func BenchmarkWriteReportArchive(b *testing.B) {
+	reports, opts := benchmarkArchive(b)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := WriteReportArchive(io.Discard, reports, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBufferedReportArchive is how archives were built before they
+// were streamed: every workbook rendered into a buffer, then copied into a
+// zip that is itself held in memory.
+func BenchmarkBufferedReportArchive(b *testing.B) {
+	reports, opts := benchmarkArchive(b)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		var archive bytes.Buffer
+		zipWriter := zip.NewWriter(&archive)
+		for _, file := range reportFiles(reports, opts) {
+			var workbook bytes.Buffer
+			if err := file.render(&workbook); err != nil {
+				b.Fatal(err)
+			}
+			fw, err := zipWriter.Create(file.name)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := fw.Write(workbook.Bytes()); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := zipWriter.Close(); err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, &archive)
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"omnituan.online/models"
+	"omnituan.online/pnl"
+)
+
+// Ways of choosing the period a report is compared with.
+const (
+	// ComparePrevious is the equally long period right before the report.
+	ComparePrevious = "previous"
+	// CompareYear is the same dates a year earlier.
+	CompareYear = "year"
+)
+
+// ComparisonPeriod returns the period [dateFrom, dateTo] is compared with
+// under mode.
+func ComparisonPeriod(mode string, dateFrom, dateTo time.Time) (from, to time.Time) {
+	if mode == CompareYear {
+		return yearEarlier(dateFrom), yearEarlier(dateTo)
+	}
+	days := int(dateTo.Sub(dateFrom).Hours()/24) + 1
+	to = dateFrom.AddDate(0, 0, -1)
+	return to.AddDate(0, 0, 1-days), to
+}
+
+// yearEarlier maps 29 February to the 28th instead of 1 March.
+func yearEarlier(t time.Time) time.Time {
+	earlier := t.AddDate(-1, 0, 0)
+	if earlier.Day() != t.Day() {
+		earlier = earlier.AddDate(0, 0, -earlier.Day())
+	}
+	return earlier
+}
+
+// Baseline is the period a report is compared with and its rows.
+type Baseline struct {
+	Mode     string
+	From, To time.Time
+	Reports  []models.ReportDetails
+}
+
+// GetBaseline fetches the rows of the period [dateFrom, dateTo] is compared
+// with under mode, like GetReportDetails. An empty mode means no comparison
+// and returns nil.
+func GetBaseline(ctx context.Context, seller Seller, mode string, dateFrom, dateTo time.Time, refresh bool, onPage PageFunc) (*Baseline, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	from, to := ComparisonPeriod(mode, dateFrom, dateTo)
+	reports, err := GetReportDetails(ctx, seller, from, to, refresh, onPage)
+	if err != nil {
+		return nil, err
+	}
+	return &Baseline{Mode: mode, From: from, To: to, Reports: reports}, nil
+}
+
+// ComputeReport is pnl.Compute with the comparison against baseline
+// attached when it is not nil.
+func ComputeReport(reports []models.ReportDetails, params pnl.Params, baseline *Baseline) pnl.Result {
+	result := pnl.Compute(reports, params)
+	if baseline == nil {
+		return result
+	}
+
+	comparison := pnl.Compare(result, pnl.Compute(baseline.Reports, params))
+	comparison.Mode = baseline.Mode
+	comparison.From = baseline.From.Format("2006-01-02")
+	comparison.To = baseline.To.Format("2006-01-02")
+	result.Comparison = &comparison
+	return result
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCancelled is returned when the caller's context ends before a service
+// finishes, typically because the HTTP client disconnected.
+var ErrCancelled = errors.New("request cancelled")
+
+func checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCancelled, err)
+	}
+	return nil
+}
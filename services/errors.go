@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"omnituan.online/wb"
+)
+
+// Kinds of service failures besides ErrCancelled; match them with
+// errors.Is. The API maps each kind to its own status code.
+var (
+	ErrInvalidPeriod = errors.New("invalid period")
+
+	ErrUpstreamUnauthorized = errors.New("WB rejected the API token")
+	ErrRateLimited          = errors.New("WB rate limit exceeded")
+	ErrUpstreamUnavailable  = errors.New("WB is unavailable")
+	ErrUpstreamTimeout      = errors.New("WB did not answer in time")
+	ErrUpstreamRejected     = errors.New("WB rejected the request")
+	ErrDecode               = errors.New("cannot decode the WB response")
+
+	ErrGeneration = errors.New("failed to generate the report")
+)
+
+// Error is a failure of one of the kinds above with what is known about it.
+type Error struct {
+	Kind error
+	// Detail explains the failure to API clients, e.g. what WB answered.
+	Detail string
+	// RequestID is the ID WB gave the failed call, for support tickets.
+	RequestID  string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message() + ": " + e.Err.Error()
+	}
+	return e.Message()
+}
+
+// Message is Error without the underlying cause, safe to show API clients.
+func (e *Error) Message() string {
+	if e.Detail != "" {
+		return e.Kind.Error() + ": " + e.Detail
+	}
+	return e.Kind.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// upstreamError classifies a failed WB call.
+func upstreamError(ctx context.Context, err error) error {
+	if ctxErr := checkContext(ctx); ctxErr != nil {
+		return ctxErr
+	}
+
+	var statusErr *wb.StatusError
+	if errors.As(err, &statusErr) {
+		e := &Error{Detail: statusErr.Detail, RequestID: statusErr.RequestID, Err: err}
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			e.Kind = ErrUpstreamUnauthorized
+		case code == http.StatusTooManyRequests:
+			e.Kind, e.RetryAfter = ErrRateLimited, statusErr.RetryAfter
+		case code >= 500:
+			e.Kind = ErrUpstreamUnavailable
+		default:
+			e.Kind = ErrUpstreamRejected
+		}
+		return e
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, wb.ErrDecode):
+		return &Error{Kind: ErrDecode, Err: err}
+	case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout():
+		return &Error{Kind: ErrUpstreamTimeout, Err: err}
+	default:
+		return &Error{Kind: ErrUpstreamUnavailable, Err: err}
+	}
+}
+
+// ParsePeriod parses a YYYY-MM-DD date range.
+func ParsePeriod(from, to string) (dateFrom, dateTo time.Time, err error) {
+	if dateFrom, err = time.Parse("2006-01-02", from); err != nil {
+		return dateFrom, dateTo, &Error{Kind: ErrInvalidPeriod, Detail: fmt.Sprintf("start %q is not a YYYY-MM-DD date", from)}
+	}
+	if dateTo, err = time.Parse("2006-01-02", to); err != nil {
+		return dateFrom, dateTo, &Error{Kind: ErrInvalidPeriod, Detail: fmt.Sprintf("end %q is not a YYYY-MM-DD date", to)}
+	}
+	if dateTo.Before(dateFrom) {
+		return dateFrom, dateTo, &Error{Kind: ErrInvalidPeriod, Detail: "end is before start"}
+	}
+	return dateFrom, dateTo, nil
+}
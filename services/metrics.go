@@ -0,0 +1,50 @@
+package services
+
+import (
+	"io"
+
+	"omnituan.online/metrics"
+)
+
+var (
+	reportPages = metrics.NewHistogram("berrio_report_pages",
+		"reportDetailByPeriod pages fetched from WB per report; cached weeks are not fetched.",
+		[]float64{0, 1, 2, 5, 10, 20, 50, 100})
+	reportRows = metrics.NewHistogram("berrio_report_rows",
+		"Realization rows fetched from WB per report.",
+		metrics.ExponentialBuckets(100, 4, 9))
+	excelDuration = metrics.NewHistogram("berrio_excel_generation_duration_seconds",
+		"Time spent rendering a report workbook.",
+		metrics.ExponentialBuckets(0.05, 2, 11), "workbook")
+	excelSize = metrics.NewHistogram("berrio_excel_size_bytes",
+		"Size of rendered report workbooks.",
+		metrics.ExponentialBuckets(16<<10, 4, 8), "workbook")
+)
+
+// fetchCounter tallies the pages and rows of one report for reportPages and
+// reportRows.
+type fetchCounter struct {
+	pages, rows int
+}
+
+func (f *fetchCounter) add(rows int) {
+	f.pages++
+	f.rows += rows
+}
+
+func (f *fetchCounter) observe() {
+	reportPages.Observe(float64(f.pages))
+	reportRows.Observe(float64(f.rows))
+}
+
+// countingWriter counts the bytes written through it for excelSize.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -1,172 +1,187 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
-	"net/http"
 	"time"
 
 	"github.com/xuri/excelize/v2"
+	"omnituan.online/i18n"
 	"omnituan.online/models"
+	"omnituan.online/pnl"
+	"omnituan.online/store"
+	"omnituan.online/wb"
 )
 
-func GetReportDetails(apiKey string, dateFrom, dateTo time.Time) ([]models.ReportDetails, error) {
-	var allReports []models.ReportDetails
-	limit := 100000
-	rrdid := int64(0) // Bắt đầu với rrdid = 0
+// PageFunc is notified after every reportDetailByPeriod page with the number
+// of rows it carried.
+type PageFunc func(rows int)
 
-	for {
-		// Tạo URL với dateFrom, dateTo, limit và rrdid
-		url := fmt.Sprintf(
-			"https://statistics-api.wildberries.ru/api/v5/supplier/reportDetailByPeriod?dateFrom=%s&dateTo=%s&limit=%d&rrdid=%d",
-			dateFrom.Format("2006-01-02"),
-			dateTo.Format("2006-01-02"),
-			limit,
-			rrdid,
-		)
-
-		client := &http.Client{}
-
-		// Tạo request
-		req, err := http.NewRequest("GET", url, nil)
+// PageHandler consumes one reportDetailByPeriod page. Returning an error
+// stops the walk.
+type PageHandler func(page []models.ReportDetails) error
+
+// ReportPageLimit is the number of rows asked per reportDetailByPeriod call;
+// WB serves at most 100000.
+var ReportPageLimit = 100000
+
+var reportStore *store.Store
+
+// SetReportStore enables the on-disk cache of fetched rows; nil disables it.
+func SetReportStore(s *store.Store) {
+	reportStore = s
+}
+
+// CheckReportStore reports whether the row cache, if enabled, is usable.
+func CheckReportStore() error {
+	if reportStore == nil {
+		return nil
+	}
+	return reportStore.Check()
+}
+
+// GetReportDetails returns the rows of [dateFrom, dateTo]. With a store set,
+// only weeks not cached yet are requested from WB, unless refresh is true.
+func GetReportDetails(ctx context.Context, seller Seller, dateFrom, dateTo time.Time, refresh bool, onPage PageFunc) ([]models.ReportDetails, error) {
+	var fetched fetchCounter
+	countPage := func(rows int) {
+		fetched.add(rows)
+		if onPage != nil {
+			onPage(rows)
+		}
+	}
+
+	if reportStore == nil {
+		reports, err := fetchReportDetails(ctx, seller.APIKey, dateFrom, dateTo, countPage)
+		if err == nil {
+			fetched.observe()
+		}
+		return reports, err
+	}
+
+	missing := store.Weeks(dateFrom, dateTo)
+	if !refresh {
+		var err error
+		missing, err = reportStore.Missing(seller.Key, dateFrom, dateTo)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %v", err)
+			return nil, err
 		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-		req.Header.Set("Content-Type", "application/json")
+	}
 
-		// Gửi request
-		res, err := client.Do(req)
+	for _, weeks := range consecutiveWeeks(missing) {
+		from, to := weeks[0], weeks[len(weeks)-1].AddDate(0, 0, 6)
+		fetchedAt := time.Now()
+		reports, err := fetchReportDetails(ctx, seller.APIKey, from, to, countPage)
 		if err != nil {
-			return nil, fmt.Errorf("failed to make request: %v", err)
+			return nil, err
 		}
-		defer res.Body.Close()
+		if err := reportStore.Put(seller.Key, reports, weeks, fetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to cache reports: %w", err)
+		}
+		slog.InfoContext(ctx, "Cached report rows", "seller", seller.Key, "rows", len(reports),
+			"from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"))
+	}
+	fetched.observe()
+
+	return reportStore.Rows(seller.Key, dateFrom, dateTo)
+}
 
-		// Xử lý rate limit (429)
-		if res.StatusCode == 429 {
-			fmt.Println("Rate limit exceeded (429), waiting for 1 minute...")
-			time.Sleep(1 * time.Minute)
-			continue
+func fetchReportDetails(ctx context.Context, apiKey string, dateFrom, dateTo time.Time, onPage PageFunc) ([]models.ReportDetails, error) {
+	var allReports []models.ReportDetails
+	err := streamReportDetails(ctx, apiKey, dateFrom, dateTo, 0, func(page []models.ReportDetails) error {
+		allReports = append(allReports, page...)
+		if onPage != nil {
+			onPage(len(page))
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allReports, nil
+}
 
-		// Kiểm tra status code
-		if res.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(res.Body)
-			return nil, fmt.Errorf("error response: status code %d, body: %s", res.StatusCode, string(body))
+// consecutiveWeeks splits sorted week starts into runs of adjacent weeks so
+// each run is fetched with one walk of the rrdid cursor.
+func consecutiveWeeks(weeks []time.Time) [][]time.Time {
+	var runs [][]time.Time
+	for i, w := range weeks {
+		if i == 0 || !w.Equal(weeks[i-1].AddDate(0, 0, 7)) {
+			runs = append(runs, nil)
 		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], w)
+	}
+	return runs
+}
 
-		// Đọc và parse body
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %v", err)
+// StreamReportDetails walks the rrdid cursor and hands every page to handle
+// as soon as it arrives, without keeping earlier pages.
+func StreamReportDetails(ctx context.Context, apiKey string, dateFrom, dateTo time.Time, handle PageHandler) error {
+	var fetched fetchCounter
+	err := streamReportDetails(ctx, apiKey, dateFrom, dateTo, 0, func(page []models.ReportDetails) error { // Bắt đầu với rrdid = 0
+		fetched.add(len(page))
+		return handle(page)
+	})
+	if err == nil {
+		fetched.observe()
+	}
+	return err
+}
+
+// streamReportDetails walks the cursor from rows newer than rrdid.
+func streamReportDetails(ctx context.Context, apiKey string, dateFrom, dateTo time.Time, rrdid int64, handle PageHandler) error {
+	if err := CheckToken(apiKey, wb.ScopeStatistics); err != nil {
+		return err
+	}
+	limit := ReportPageLimit
+
+	for {
+		if err := checkContext(ctx); err != nil {
+			return err
 		}
 
-		var reports []models.ReportDetails
-		if err := json.Unmarshal(body, &reports); err != nil {
-			return nil, fmt.Errorf("failed to decode JSON: %v", err)
+		reports, err := wbClient.ReportDetailByPeriod(ctx, apiKey, dateFrom, dateTo, limit, rrdid)
+		if err != nil {
+			return upstreamError(ctx, err)
 		}
 
 		// Thoát nếu không còn dữ liệu
 		if len(reports) == 0 {
-			fmt.Println("No more data to fetch.")
+			slog.DebugContext(ctx, "No more report rows", "rrdid", rrdid)
 			break
 		}
 
-		allReports = append(allReports, reports...)
+		if err := handle(reports); err != nil {
+			return err
+		}
 
 		// Cập nhật rrdid từ bản ghi cuối cùng
 		rrdid = reports[len(reports)-1].RrdID
-		fmt.Printf("Fetched %d records, next rrdid: %d\n", len(reports), rrdid)
+		slog.DebugContext(ctx, "Fetched report page", "rows", len(reports), "next_rrdid", rrdid)
 
 		if len(reports) < limit {
-			fmt.Println("Reached end of data (less than limit).")
+			slog.DebugContext(ctx, "Reached end of report rows", "rows", len(reports), "limit", limit)
 			break
 		}
 	}
 
-	return allReports, nil
+	return nil
 }
 
-func GenerateDetailedExcel(reports []models.ReportDetails) ([]byte, error) {
+func GenerateDetailedExcel(w io.Writer, reports []models.ReportDetails, locale i18n.Locale) error {
 	f := excelize.NewFile()
+	defer f.Close()
 	sheet1 := "Sheet1"
 	sw, err := f.NewStreamWriter(sheet1)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	headers := []any{
-		"STT",                              // №
-		"Mã giao hàng",                     // Номер поставки
-		"Loại sản phẩm",                    // Предмет
-		"Mã hàng",                          // Код номенклатуры
-		"Thương hiệu",                      // Бренд
-		"Mã nhà cung cấp",                  // Артикул поставщика
-		"Tên sản phẩm",                     // Название
-		"Kích thước",                       // Размер
-		"Mã vạch",                          // Баркод
-		"Loại tài liệu",                    // Тип документа
-		"Lý do giao dịch",                  // Обоснование для оплаты
-		"Ngày đặt hàng",                    // Дата заказа покупателем
-		"Ngày bán",                         // Дата продажи
-		"Số lượng",                         // Кол-во
-		"Giá niêm yết",                     // Цена розничная
-		"Doanh thu Wildberries (đã bán)",   // Вайлдберриз реализовал Товар (Пр)
-		"Giảm giá theo thỏa thuận (%)",     // Согласованный продуктовый дисконт, %
-		"Khuyến mãi mã giảm (%)",           // Промокод %
-		"Tổng giảm giá sau thỏa thuận (%)", // Итоговая согласованная скидка, %
-		"Giá sau giảm",                     // Цена розничная с учетом согласованной скидки
-		"Giảm giá do đánh giá (%)",         // Размер снижения кВВ из-за рейтинга, %
-		"Giảm giá do khuyến mãi (%)",       // Размер изменения кВВ из-за акции, %
-		"Chiết khấu khách hàng thân thiết (SPP) (%)", // Скидка постоянного Покупателя (СПП), %
-		"Hoa hồng (%)",                    // Размер кВВ, %
-		"Hoa hồng cơ bản không VAT (%)",   // Размер  кВВ без НДС, % Базовый
-		"Hoa hồng cuối không VAT (%)",     // Итоговый кВВ без НДС, %
-		"Hoa hồng Wildberries (chưa VAT)", // Вознаграждение с продаж до вычета услуг поверенного, без НДС
-		"Hoàn phí giao/hoàn trả",          // Возмещение за выдачу и возврат товаров на ПВЗ
-		"Phí thanh toán",                  // Эквайринг/Комиссии за организацию платежей
-		"Tỷ lệ phí thanh toán (%)",        // Размер комиссии за эквайринг/Комиссии за организацию платежей, %
-		"Hình thức thanh toán",            // Тип платежа за Эквайринг/Комиссии за организацию платежей
-		"Phí Wildberries (chưa VAT)",      // Вознаграждение Вайлдберриз (ВВ), без НДС
-		"VAT trên phí Wildberries",        // НДС с Вознаграждения Вайлдберриз
-		"Tiền thực nhận",                  // К перечислению Продавцу за реализованный Товар
-		"Số lần giao",                     // Количество доставок
-		"Số lần hoàn",                     // Количество возврата
-		"Chi phí giao hàng",               // Услуги по доставке товара покупателю
-		"Ngày bắt đầu phí cố định",        // Дата начала действия фиксации
-		"Ngày kết thúc phí cố định",       // Дата конца действия фиксации
-		"Dịch vụ giao hàng có tính phí",   // Признак услуги платной доставки
-		"Tổng tiền phạt",                  // Общая сумма штрафов
-		"Điều chỉnh phí Wildberries",      // Корректировка Вознаграждения Вайлдберриз (ВВ)
-		"Loại logistics/phạt/điều chỉnh",  // Виды логистики, штрафов и корректировок ВВ
-		"Mã nhãn dán (Sticker MP)",        // Стикер МП
-		"Ngân hàng thanh toán",            // Наименование банка-эквайера
-		"Mã văn phòng",                    // Номер офиса
-		"Tên văn phòng giao hàng",         // Наименование офиса доставки
-		"Mã số thuế đối tác",              // ИНН партнера
-		"Tên đối tác",                     // Партнер
-		"Kho hàng",                        // Склад
-		"Quốc gia",                        // Страна
-		"Loại hộp",                        // Тип коробов
-		"Số tờ khai hải quan",             // Номер таможенной декларации
-		"Mã đơn lắp ráp",                  // Номер сборочного задания
-		"Mã định danh (KIZ)",              // Код маркировки
-		"Mã sản phẩm (ШК)",                // ШК
-		"Mã giao dịch (Srid)",             // Srid
-		"Hoàn phí vận chuyển/kho",         // Возмещение издержек по перевозке/по складским операциям с товаром
-		"Đơn vị vận chuyển",               // Организатор перевозки
-		"Phí lưu kho",                     // Хранение
-		"Khoản trừ khác",                  // Удержания
-		"Phí nhận hàng",                   // Платная приемка
-		"Hệ số kho cố định",               // Фиксированный коэффициент склада по поставке
-		"Bán cho công ty",                 // Признак продажи юридическому лицу
-		"Số hộp nhận hàng tính phí",       // Номер короба для платной приемки
-		"Giảm giá đồng tài trợ",           // Скидка по программе софинансирования
-		"Giảm giá Wibes (%)",              // Скидка Wibes, %
-	}
+	headers := locale.DetailedHeaders()
 
 	headerStyle, _ := f.NewStyle(&excelize.Style{
 		Font:      &excelize.Font{Bold: true},
@@ -181,7 +196,7 @@ func GenerateDetailedExcel(reports []models.ReportDetails) ([]byte, error) {
 	// 	f.SetCellStyle(sheet, cell, cell, headerStyle)
 	// }
 	if err := sw.SetRow("A1", headers, excelize.RowOpts{StyleID: headerStyle, Height: 24}); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Ghi dữ liệu
@@ -204,18 +219,18 @@ func GenerateDetailedExcel(reports []models.ReportDetails) ([]byte, error) {
 			r.Quantity,                     // Кол-во
 			r.RetailPrice,                  // Цена розничная
 			r.RetailAmount,                 // Вайлдберриз реализовал Товар (Пр)
-			0,                              // Согласованный продуктовый дисконт, %
-			"",                             // Промокод %
-			0,                              // Итоговая согласованная скидка, %
-			r.RetailPrice,                  // Цена розничная с учетом согласованной скидки
-			0,                              // Размер снижения кВВ из-за рейтинга, %
-			0,                              // Размер изменения кВВ из-за акции, %
+			r.ProductDiscountForReport,     // Согласованный продуктовый дисконт, %
+			r.SupplierPromo,                // Промокод %
+			r.SalePercent,                  // Итоговая согласованная скидка, %
+			r.RetailPriceWithDiscRub,       // Цена розничная с учетом согласованной скидки
+			r.SupRatingPrcUp,               // Размер снижения кВВ из-за рейтинга, %
+			r.IsKgvpV2,                     // Размер изменения кВВ из-за акции, %
 			r.PpvzSppPrc,                   // Скидка постоянного Покупателя (СПП), %
 			math.Round(r.CommissionPercent*100) / 100, // Размер кВВ, %
 			math.Round(r.PpvzKvwPrcBase*100) / 100,    // Размер  кВВ без НДС, % Базовый
 			math.Round(r.PpvzKvwPrc*100) / 100,        // Итоговый кВВ без НДС, %
 			r.PpvzSalesCommission,                     // Вознаграждение с продаж до вычета услуг поверенного, без НДС
-			0,                                         //Возмещение за выдачу и возврат товаров на ПВЗ
+			r.PpvzReward,                              // Возмещение за выдачу и возврат товаров на ПВЗ
 			r.AcquiringFee,                            // Эквайринг/Комиссии за организацию платежей
 			r.AcquiringPercent,                        // Размер комиссии за эквайринг/Комиссии за организацию платежей, %
 			r.PaymentProcessing,                       // Тип платежа за Эквайринг/Комиссии за организацию платежей
@@ -227,20 +242,20 @@ func GenerateDetailedExcel(reports []models.ReportDetails) ([]byte, error) {
 			r.DeliveryRub,                             // Услуги по доставке товара покупателю
 			r.FixTariffDateFrom,                       // Дата начала действия фиксации
 			r.FixTariffDateTo,                         // Дата конца действия фиксации
-			"",                                        // Признак услуги платной доставки
-			0,                                         // Общая сумма штрафов
-			0,                                         // Корректировка Вознаграждения Вайлдберриз (ВВ)
+			yesNo(r.SrvDbs, locale),                   // Признак услуги платной доставки
+			r.Penalty,                                 // Общая сумма штрафов
+			r.AdditionalPayment,                       // Корректировка Вознаграждения Вайлдберриз (ВВ)
 			r.BonusTypeName,                           // Виды логистики, штрафов и корректировок ВВ
 			r.StickerID,                               // Стикер МП
 			r.AcquiringBank,                           // Наименование банка-эквайера
 			r.PpvzOfficeID,                            // Номер офиса
 			r.PpvzOfficeName,                          // Наименование офиса доставки
-			"",                                        // ИНН партнера
-			"",                                        // Партнер
+			r.PpvzInn,                                 // ИНН партнера
+			r.PpvzSupplierName,                        // Партнер
 			r.OfficeName,                              // Склад
 			r.SiteCountry,                             // Страна
 			r.GiBoxTypeName,                           // Тип коробов
-			"",                                        // Номер таможенной декларации
+			r.DeclarationNumber,                       // Номер таможенной декларации
 			r.AssemblyID,                              // Номер сборочного задания
 			r.Kiz,                                     // Код маркировки
 			r.ShkID,                                   // ШК
@@ -251,43 +266,45 @@ func GenerateDetailedExcel(reports []models.ReportDetails) ([]byte, error) {
 			r.Deduction,                               // Удержания
 			r.Acceptance,                              // Платная приемка
 			r.DlvPrc,                                  // Фиксированный коэффициент склада по поставке
-			"Нет",                                     // Признак продажи юридическому лицу
-			0,                                         // Номер короба для платной приемки
-			0,                                         // Скидка по программе софинансирования
-			"",                                        // Скидка Wibes, %
+			yesNo(r.IsLegalEntity, locale),            // Признак продажи юридическому лицу
+			r.TrbxID,                                  // Номер короба для платной приемки
+			r.InstallmentCofinancingAmount,            // Скидка по программе софинансирования
+			r.WibesWbDiscountPercent,                  // Скидка Wibes, %
 		}
 		if err := sw.SetRow(fmt.Sprintf("A%d", row), data); err != nil {
-			return nil, fmt.Errorf("failed to write row %d: %w", row, err)
+			return fmt.Errorf("failed to write row %d: %w", row, err)
 		}
 
 	}
 
 	if err := sw.Flush(); err != nil {
-		return nil, fmt.Errorf("failed to flush stream writer: %w", err)
+		return fmt.Errorf("failed to flush stream writer: %w", err)
 	}
 
-	var buf bytes.Buffer
-	if err := f.Write(&buf); err != nil {
-		return nil, err
+	return f.Write(w)
+}
+
+func yesNo(v bool, locale i18n.Locale) string {
+	if v {
+		return locale.T(i18n.Yes)
 	}
-	return buf.Bytes(), nil
+	return locale.T(i18n.No)
 }
 
-func GenerateReportExcel(reports []models.ReportDetails, taxPt, discountPt float64) ([]byte, error) {
-	var grossRevenue float64          // Doanh thu gộp
-	var netRevenue float64            // Doanh thu thuần
-	var reductionInRevenue float64    // Giảm trừ doanh thu
-	var logisticsExpenses float64     // Chi phí logistic
-	var otherExpenses float64         // Chi phí khác
-	var revenueExcludingCOGS float64  // Doanh thu chưa trừ giá vốn
-	var estimatedCOGS float64         // Giá vốn ước lượng
-	var revenueExcludingTaxes float64 // Doanh thu giảm trừ thuế
-	var grossProfitToal float64       // Lãi gộp
-	var tax float64                   // Thuế(%)
-	var taxFinal float64              // Thuế phải đóng
-	var netProfit float64             // Lãi ròng
+// Cột đầu tiên của từng bảng trên sheet Report, tính từ 0
+const (
+	colSales     = 0  // A
+	colReturns   = 5  // F
+	colLogistics = 10 // K
+	colCancelled = 14 // O
+	colOther     = 18 // S
+	colSummary   = 22 // W
+	colUnmatched = 36 // AK
+)
 
+func GenerateReportExcel(w io.Writer, result pnl.Result, locale i18n.Locale) error {
 	f := excelize.NewFile()
+	defer f.Close()
 	sheet := "Report"
 	f.SetSheetName("Sheet1", sheet)
 
@@ -349,143 +366,367 @@ func GenerateReportExcel(reports []models.ReportDetails, taxPt, discountPt float
 		},
 	})
 
+	// The tables sit side by side, so each streamed row carries the cells of
+	// every table that reaches it.
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	width := colSummary + 12
+	if len(result.UnmatchedSKUs) > 0 {
+		width = colUnmatched + 1
+	}
+	titles := make([]any, width)
+	headers := make([]any, width)
+	span := func(row []any, from, to, style int, values ...any) {
+		for i := from; i <= to; i++ {
+			cell := excelize.Cell{StyleID: style}
+			if i-from < len(values) {
+				cell.Value = values[i-from]
+			}
+			row[i] = cell
+		}
+	}
+
 	// Bảng Doanh thu (A1:C3+)
-	f.SetCellValue(sheet, "A1", "BẢNG DOANH THU")
-	f.MergeCell(sheet, "A1", "C1")
-	f.SetCellStyle(sheet, "A1", "C1", headerStyleLight)
-	f.SetCellValue(sheet, "A2", "Артикул поставщика")
-	f.SetCellValue(sheet, "B2", "Giá đăng bán")
-	f.SetCellValue(sheet, "C2", "Tiền chuyển cho hàng hóa đã bán chưa bao gồm chi phí logistic và chi phí khác")
-	f.SetCellStyle(sheet, "A2", "C2", titleStyleDark)
-	row := 3
-	for _, r := range reports {
-		if r.SaName != "" && r.DocTypeName == "Продажа" {
-			grossRevenue += r.RetailPrice
-			netRevenue += r.PpvzForPay
-			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), r.SaName)
-			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), r.RetailPrice)
-			f.SetCellValue(sheet, fmt.Sprintf("C%d", row), r.PpvzForPay)
-			row++
-		}
-	}
-
-	f.SetCellValue(sheet, "F1", "BẢNG HÀNG MUA BỊ TRẢ LẠI")
-	f.MergeCell(sheet, "F1", "H1")
-	f.SetCellStyle(sheet, "F1", "H1", headerStyleLight)
-	f.SetCellValue(sheet, "F2", "Артикул поставщика")
-	f.SetCellValue(sheet, "G2", "Giá gốc đăng bán")
-	f.SetCellValue(sheet, "H2", "Giá trả lại")
-	f.SetCellStyle(sheet, "F2", "H2", titleStyleDark)
-	row = 3
-	for _, r := range reports {
-		if r.DocTypeName == "Возврат" {
-			revenueExcludingTaxes += r.RetailPrice
-			reductionInRevenue += r.PpvzForPay
-			f.SetCellValue(sheet, fmt.Sprintf("F%d", row), r.SaName)
-			f.SetCellValue(sheet, fmt.Sprintf("G%d", row), r.RetailPrice)
-			f.SetCellValue(sheet, fmt.Sprintf("H%d", row), r.PpvzForPay)
-			row++
-		}
-	}
-
-	f.SetCellValue(sheet, "K1", "BẢNG PHÍ LOGISTIC")
-	f.MergeCell(sheet, "K1", "L1")
-	f.SetCellStyle(sheet, "K1", "L1", headerStyleLight)
-	f.SetCellValue(sheet, "K2", "Артикул поставщика")
-	f.SetCellValue(sheet, "L2", "Chi phí logistic")
-	f.SetCellStyle(sheet, "K2", "L2", titleStyleDark)
-	row = 3
-	for _, r := range reports {
-		if r.SupplierOperName == "Логистика" {
-			logisticsExpenses += r.DeliveryRub
-			f.SetCellValue(sheet, fmt.Sprintf("K%d", row), r.SaName)
-			f.SetCellValue(sheet, fmt.Sprintf("L%d", row), r.DeliveryRub)
-			row++
-		}
-	}
-
-	f.SetCellValue(sheet, "O1", "BẢNG PHÍ ĐƠN HÀNG BỊ HỦY OR KHÔNG MUA")
-	f.MergeCell(sheet, "O1", "P1")
-	f.SetCellStyle(sheet, "O1", "P1", headerStyleLight)
-	f.SetCellValue(sheet, "O2", "Артикул поставщика")
-	f.SetCellValue(sheet, "P2", "phí vận chuyển hàng trả lại")
-	f.SetCellStyle(sheet, "O2", "P2", titleStyleDark)
-	row = 3
-	for _, r := range reports {
-		if r.SupplierOperName == "Логистика" && r.ReturnAmount == 1 {
-			f.SetCellValue(sheet, fmt.Sprintf("O%d", row), r.SaName)
-			f.SetCellValue(sheet, fmt.Sprintf("P%d", row), r.DeliveryRub)
-			row++
-		}
-	}
-
-	f.SetCellValue(sheet, "S1", "BẢNG CHI PHÍ KHÁC")
-	f.MergeCell(sheet, "S1", "T1")
-	f.SetCellStyle(sheet, "S1", "T1", headerStyleLight)
-	f.SetCellValue(sheet, "S2", "Chi phí khác")
-	f.SetCellValue(sheet, "T2", "Số tiền")
-	f.SetCellStyle(sheet, "S2", "T2", titleStyleDark)
-	f.SetCellValue(sheet, "S3", "Tiền phạt")
-	f.SetCellValue(sheet, "S4", "Chi phí lưu trữ")
-	f.SetCellValue(sheet, "S5", "Chi phí quảng cáo")
-	f.SetCellValue(sheet, "S6", "Chi phí chấp nhận")
-	f.SetCellValue(sheet, "S7", "Tổng")
-	var fines float64
-	var storageCosts float64
-	var advCosts float64
-	var acceptanceCosts float64
-	for _, r := range reports {
-		fines += r.Penalty
-		storageCosts += r.StorageFee
-		advCosts += r.Deduction
-		acceptanceCosts += r.Acceptance
-	}
-	otherExpenses = fines + storageCosts + advCosts + acceptanceCosts
-	f.SetCellValue(sheet, "T3", fines)           // Tiền phạt
-	f.SetCellValue(sheet, "T4", storageCosts)    // Chi phí lưu trữ
-	f.SetCellValue(sheet, "T5", advCosts)        // Chi phí quảng cáo
-	f.SetCellValue(sheet, "T6", acceptanceCosts) // Chi phí chấp nhận
-	f.SetCellValue(sheet, "T7", otherExpenses)   // Tổng
-
-	revenueExcludingCOGS = netRevenue - reductionInRevenue - logisticsExpenses - otherExpenses
-	estimatedCOGS = (grossRevenue - revenueExcludingTaxes) / discountPt
-	grossProfitToal = revenueExcludingCOGS - estimatedCOGS
-	tax = (grossRevenue - revenueExcludingTaxes) * taxPt
-	taxFinal = (netRevenue - reductionInRevenue) * taxPt
-	netProfit = grossProfitToal - taxFinal
-	f.SetCellValue(sheet, "W1", "BẢNG TỔNG KẾT")
-	f.MergeCell(sheet, "W1", "AH1")
-	f.SetCellStyle(sheet, "W1", "AH1", headerStyleLight)
-	f.SetCellValue(sheet, "W2", "Doanh thu theo giá gốc sản phẩm")
-	f.SetCellValue(sheet, "X2", "Doanh thu sau khi trừ phí WB")
-	f.SetCellValue(sheet, "Y2", "Giảm trừ doanh thu(hàng trả lại)")
-	f.SetCellValue(sheet, "Z2", "Chi phí logistic")
-	f.SetCellValue(sheet, "AA2", "Chi phí khác")
-	f.SetCellValue(sheet, "AB2", "Doanh thu chưa trừ giá vốn")
-	f.SetCellValue(sheet, "AC2", "Giá vốn ước lượng")
-	f.SetCellValue(sheet, "AD2", "Doanh thu giảm trừ thuế")
-	f.SetCellValue(sheet, "AE2", "Lãi trước thuế và chi phí khác")
-	f.SetCellValue(sheet, "AF2", fmt.Sprintf("Thuế(%.2f%%)", taxPt*100))
-	f.SetCellValue(sheet, "AG2", "Thuế phải đóng")
-	f.SetCellValue(sheet, "AH2", "Lợi nhuận thực nhận về sau khi trừ toàn bộ phí")
-	f.SetCellStyle(sheet, "W2", "AH2", titleStyleDark)
-
-	f.SetCellValue(sheet, "W3", math.Round(grossRevenue*100)/100)
-	f.SetCellValue(sheet, "X3", math.Round(netRevenue*100)/100)
-	f.SetCellValue(sheet, "Y3", math.Round(reductionInRevenue*100)/100)
-	f.SetCellValue(sheet, "Z3", math.Round(logisticsExpenses*100)/100)
-	f.SetCellValue(sheet, "AA3", math.Round(otherExpenses*100)/100)
-	f.SetCellValue(sheet, "AB3", math.Round(revenueExcludingCOGS*100)/100)
-	f.SetCellValue(sheet, "AC3", math.Round(estimatedCOGS*100)/100)
-	f.SetCellValue(sheet, "AD3", math.Round(revenueExcludingTaxes*100)/100)
-	f.SetCellValue(sheet, "AE3", math.Round(grossProfitToal*100)/100)
-	f.SetCellValue(sheet, "AF3", math.Round(tax*100)/100)
-	f.SetCellValue(sheet, "AG3", math.Round(taxFinal*100)/100)
-	f.SetCellValue(sheet, "AH3", math.Round(netProfit*100)/100)
-
-	var buf bytes.Buffer
-	if err := f.Write(&buf); err != nil {
-		return nil, err
+	span(titles, colSales, colSales+2, headerStyleLight, locale.T(i18n.SalesTitle))
+	span(headers, colSales, colSales+2, titleStyleDark,
+		locale.T(i18n.SupplierArticle), locale.T(i18n.SalesRetailPrice), locale.T(i18n.SalesForPay))
+
+	span(titles, colReturns, colReturns+2, headerStyleLight, locale.T(i18n.ReturnsTitle))
+	span(headers, colReturns, colReturns+2, titleStyleDark,
+		locale.T(i18n.SupplierArticle), locale.T(i18n.ReturnsRetailPrice), locale.T(i18n.ReturnsForPay))
+
+	span(titles, colLogistics, colLogistics+1, headerStyleLight, locale.T(i18n.LogisticsTitle))
+	span(headers, colLogistics, colLogistics+1, titleStyleDark,
+		locale.T(i18n.SupplierArticle), locale.T(i18n.LogisticsCost))
+
+	span(titles, colCancelled, colCancelled+1, headerStyleLight, locale.T(i18n.CancelledTitle))
+	span(headers, colCancelled, colCancelled+1, titleStyleDark,
+		locale.T(i18n.SupplierArticle), locale.T(i18n.CancelledCost))
+
+	span(titles, colOther, colOther+1, headerStyleLight, locale.T(i18n.OtherTitle))
+	span(headers, colOther, colOther+1, titleStyleDark,
+		locale.T(i18n.OtherExpense), locale.T(i18n.OtherAmount))
+
+	summary := result.Summary
+	span(titles, colSummary, colSummary+11, headerStyleLight, locale.T(i18n.SummaryTitle))
+	span(headers, colSummary, colSummary+11, titleStyleDark,
+		locale.T(i18n.SummaryGrossRevenue),
+		locale.T(i18n.SummaryNetRevenue),
+		locale.T(i18n.SummaryReductionInRevenue),
+		locale.T(i18n.SummaryLogistics),
+		locale.T(i18n.SummaryOtherExpenses),
+		locale.T(i18n.SummaryRevenueExcludingCOGS),
+		locale.T(i18n.SummaryCOGS),
+		locale.T(i18n.SummaryRevenueExcludingTaxes),
+		locale.T(i18n.SummaryGrossProfit),
+		fmt.Sprintf(locale.T(i18n.SummaryTaxRate), summary.TaxRate*100),
+		locale.T(i18n.SummaryTaxFinal),
+		locale.T(i18n.SummaryNetProfit),
+	)
+
+	if len(result.UnmatchedSKUs) > 0 {
+		span(titles, colUnmatched, colUnmatched, headerStyleLight, locale.T(i18n.UnmatchedTitle))
+		span(headers, colUnmatched, colUnmatched, titleStyleDark, locale.T(i18n.SupplierArticle))
+	}
+
+	if err := sw.SetRow("A1", titles); err != nil {
+		return err
+	}
+	if err := sw.SetRow("A2", headers); err != nil {
+		return err
+	}
+	for _, merge := range [][2]string{{"A1", "C1"}, {"F1", "H1"}, {"K1", "L1"}, {"O1", "P1"}, {"S1", "T1"}, {"W1", "AH1"}} {
+		if err := sw.MergeCell(merge[0], merge[1]); err != nil {
+			return err
+		}
+	}
+
+	other := result.OtherExpenses
+	otherRows := [][2]any{
+		{locale.T(i18n.OtherFines), other.Fines},             // Tiền phạt
+		{locale.T(i18n.OtherStorage), other.Storage},         // Chi phí lưu trữ
+		{locale.T(i18n.OtherAdvertising), other.Advertising}, // Chi phí quảng cáo
+		{locale.T(i18n.OtherAcceptance), other.Acceptance},   // Chi phí chấp nhận
+		{locale.T(i18n.OtherTotal), other.Total},             // Tổng
+	}
+	summaryRow := []any{
+		math.Round(summary.GrossRevenue*100) / 100,
+		math.Round(summary.NetRevenue*100) / 100,
+		math.Round(summary.ReductionInRevenue*100) / 100,
+		math.Round(summary.LogisticsExpenses*100) / 100,
+		math.Round(summary.OtherExpenses*100) / 100,
+		math.Round(summary.RevenueExcludingCOGS*100) / 100,
+		math.Round(summary.EstimatedCOGS*100) / 100,
+		math.Round(summary.RevenueExcludingTaxes*100) / 100,
+		math.Round(summary.GrossProfit*100) / 100,
+		math.Round(summary.Tax*100) / 100,
+		math.Round(summary.TaxFinal*100) / 100,
+		math.Round(summary.NetProfit*100) / 100,
+	}
+
+	rows := max(len(result.Sales), len(result.Returns), len(result.Logistics),
+		len(result.CancelledLogistics), len(otherRows), len(result.UnmatchedSKUs))
+	for i := range rows {
+		row := make([]any, width)
+		if i < len(result.Sales) {
+			r := result.Sales[i]
+			row[colSales], row[colSales+1], row[colSales+2] = r.SaName, r.RetailPrice, r.ForPay
+		}
+		if i < len(result.Returns) {
+			r := result.Returns[i]
+			row[colReturns], row[colReturns+1], row[colReturns+2] = r.SaName, r.RetailPrice, r.ForPay
+		}
+		if i < len(result.Logistics) {
+			r := result.Logistics[i]
+			row[colLogistics], row[colLogistics+1] = r.SaName, r.DeliveryRub
+		}
+		if i < len(result.CancelledLogistics) {
+			r := result.CancelledLogistics[i]
+			row[colCancelled], row[colCancelled+1] = r.SaName, r.DeliveryRub
+		}
+		if i < len(otherRows) {
+			row[colOther], row[colOther+1] = otherRows[i][0], otherRows[i][1]
+		}
+		if i == 0 {
+			copy(row[colSummary:], summaryRow)
+		}
+		if i < len(result.UnmatchedSKUs) {
+			row[colUnmatched] = result.UnmatchedSKUs[i]
+		}
+		if err := sw.SetRow(fmt.Sprintf("A%d", i+3), row); err != nil {
+			return fmt.Errorf("failed to write row %d: %w", i+3, err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush stream writer: %w", err)
+	}
+
+	// Sheet lãi/lỗ theo từng SKU, SKU lỗ nhiều nhất lên đầu
+	skuSheet := "SKU"
+	if _, err := f.NewSheet(skuSheet); err != nil {
+		return err
+	}
+	skuWriter, err := f.NewStreamWriter(skuSheet)
+	if err != nil {
+		return err
+	}
+	skuLabels := []any{
+		locale.T(i18n.SupplierArticle),
+		locale.T(i18n.SKUSize),
+		locale.T(i18n.SKUUnitsSold),
+		locale.T(i18n.SKUUnitsReturned),
+		locale.T(i18n.SKURevenue),
+		locale.T(i18n.SKUForPay),
+		locale.T(i18n.LogisticsCost),
+		locale.T(i18n.OtherStorage),
+		locale.T(i18n.OtherFines),
+		locale.T(i18n.SKUOtherDeductions),
+		locale.T(i18n.SummaryCOGS),
+		locale.T(i18n.SKUTax),
+		locale.T(i18n.SKUNetProfit),
+	}
+	skuTitles := make([]any, len(skuLabels))
+	skuHeaders := make([]any, len(skuLabels))
+	span(skuTitles, 0, len(skuLabels)-1, headerStyleLight, locale.T(i18n.SKUTitle))
+	span(skuHeaders, 0, len(skuLabels)-1, titleStyleDark, skuLabels...)
+	if err := skuWriter.SetRow("A1", skuTitles); err != nil {
+		return err
+	}
+	if err := skuWriter.SetRow("A2", skuHeaders); err != nil {
+		return err
+	}
+	lastCol, _ := excelize.ColumnNumberToName(len(skuLabels))
+	if err := skuWriter.MergeCell("A1", lastCol+"1"); err != nil {
+		return err
+	}
+	for i, sku := range result.Rounded().SKUs {
+		saName := sku.SaName
+		if saName == "" {
+			saName = locale.T(i18n.SKUGeneralCosts)
+		}
+		err := skuWriter.SetRow(fmt.Sprintf("A%d", i+3), []any{
+			saName,
+			sku.TsName,
+			sku.UnitsSold,
+			sku.UnitsReturned,
+			sku.Revenue,
+			sku.ForPay,
+			sku.Logistics,
+			sku.Storage,
+			sku.Penalties,
+			sku.OtherDeductions,
+			sku.COGS,
+			sku.Tax,
+			sku.NetProfit,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write SKU row %d: %w", i+3, err)
+		}
+	}
+	if err := skuWriter.Flush(); err != nil {
+		return fmt.Errorf("failed to flush stream writer: %w", err)
+	}
+
+	if result.Comparison != nil {
+		if err := writeComparisonSheet(f, result, locale, headerStyleLight, titleStyleDark); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
+
+// Cột đầu tiên của từng bảng trên sheet Comparison
+const (
+	colCompareSummary = 0 // A
+	colCompareSKU     = 6 // G
+)
+
+// summaryLabels names the figures of pnl.Comparison.Summary.
+var summaryLabels = map[string]i18n.Key{
+	"grossRevenue":          i18n.SummaryGrossRevenue,
+	"netRevenue":            i18n.SummaryNetRevenue,
+	"reductionInRevenue":    i18n.SummaryReductionInRevenue,
+	"logisticsExpenses":     i18n.SummaryLogistics,
+	"otherExpenses":         i18n.SummaryOtherExpenses,
+	"revenueExcludingCOGS":  i18n.SummaryRevenueExcludingCOGS,
+	"estimatedCOGS":         i18n.SummaryCOGS,
+	"revenueExcludingTaxes": i18n.SummaryRevenueExcludingTaxes,
+	"grossProfit":           i18n.SummaryGrossProfit,
+	"taxFinal":              i18n.SummaryTaxFinal,
+	"netProfit":             i18n.SummaryNetProfit,
+}
+
+// compareSKUFigures are the columns of the SKU comparison, in the order of
+// the SKU sheet.
+var compareSKUFigures = []struct {
+	label i18n.Key
+	value func(pnl.SKUDelta) pnl.Delta
+}{
+	{i18n.SKUUnitsSold, func(d pnl.SKUDelta) pnl.Delta { return d.UnitsSold }},
+	{i18n.SKUUnitsReturned, func(d pnl.SKUDelta) pnl.Delta { return d.UnitsReturned }},
+	{i18n.SKURevenue, func(d pnl.SKUDelta) pnl.Delta { return d.Revenue }},
+	{i18n.SKUForPay, func(d pnl.SKUDelta) pnl.Delta { return d.ForPay }},
+	{i18n.LogisticsCost, func(d pnl.SKUDelta) pnl.Delta { return d.Logistics }},
+	{i18n.OtherStorage, func(d pnl.SKUDelta) pnl.Delta { return d.Storage }},
+	{i18n.OtherFines, func(d pnl.SKUDelta) pnl.Delta { return d.Penalties }},
+	{i18n.SKUOtherDeductions, func(d pnl.SKUDelta) pnl.Delta { return d.OtherDeductions }},
+	{i18n.SummaryCOGS, func(d pnl.SKUDelta) pnl.Delta { return d.COGS }},
+	{i18n.SKUTax, func(d pnl.SKUDelta) pnl.Delta { return d.Tax }},
+	{i18n.SKUNetProfit, func(d pnl.SKUDelta) pnl.Delta { return d.NetProfit }},
+}
+
+// writeComparisonSheet adds the summary and per-SKU deltas side by side,
+// each figure as this period, compared period, change and change in percent.
+func writeComparisonSheet(f *excelize.File, result pnl.Result, locale i18n.Locale, headerStyle, titleStyle int) error {
+	comparison := result.Comparison
+	sheet := "Comparison"
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	deltaLabels := []any{
+		locale.T(i18n.CompareCurrent),
+		locale.T(i18n.ComparePrevious),
+		locale.T(i18n.CompareChange),
+		locale.T(i18n.CompareChangePct),
+	}
+	width := colCompareSKU + 2 + len(compareSKUFigures)*len(deltaLabels)
+
+	rows := [3][]any{make([]any, width), make([]any, width), make([]any, width)}
+	set := func(row []any, col, style int, value any) {
+		row[col] = excelize.Cell{StyleID: style, Value: value}
+	}
+	var merges [][2]string
+	merge := func(fromCol, fromRow, toCol, toRow int) {
+		from, _ := excelize.CoordinatesToCellName(fromCol+1, fromRow)
+		to, _ := excelize.CoordinatesToCellName(toCol+1, toRow)
+		merges = append(merges, [2]string{from, to})
+	}
+
+	// Bảng tổng kết: tiêu đề ở dòng 1, tên cột gộp dòng 2-3
+	summaryTitle := fmt.Sprintf(locale.T(i18n.CompareTitle), comparison.From, comparison.To)
+	for i := range 5 {
+		set(rows[0], colCompareSummary+i, headerStyle, nil)
+		set(rows[2], colCompareSummary+i, titleStyle, nil)
+	}
+	set(rows[0], colCompareSummary, headerStyle, summaryTitle)
+	merge(colCompareSummary, 1, colCompareSummary+4, 1)
+	for i, label := range append([]any{locale.T(i18n.CompareFigure)}, deltaLabels...) {
+		set(rows[1], colCompareSummary+i, titleStyle, label)
+		merge(colCompareSummary+i, 2, colCompareSummary+i, 3)
+	}
+
+	// Bảng SKU: mỗi chỉ tiêu chiếm 4 cột
+	for i := colCompareSKU; i < width; i++ {
+		set(rows[0], i, headerStyle, nil)
+	}
+	set(rows[0], colCompareSKU, headerStyle, locale.T(i18n.CompareSKUTitle))
+	merge(colCompareSKU, 1, width-1, 1)
+	for i, label := range []any{locale.T(i18n.SupplierArticle), locale.T(i18n.SKUSize)} {
+		set(rows[1], colCompareSKU+i, titleStyle, label)
+		set(rows[2], colCompareSKU+i, titleStyle, nil)
+		merge(colCompareSKU+i, 2, colCompareSKU+i, 3)
+	}
+	for i, figure := range compareSKUFigures {
+		col := colCompareSKU + 2 + i*len(deltaLabels)
+		for j, label := range deltaLabels {
+			set(rows[1], col+j, titleStyle, nil)
+			set(rows[2], col+j, titleStyle, label)
+		}
+		set(rows[1], col, titleStyle, locale.T(figure.label))
+		merge(col, 2, col+len(deltaLabels)-1, 2)
+	}
+
+	for i, row := range rows {
+		if err := sw.SetRow(fmt.Sprintf("A%d", i+1), row); err != nil {
+			return err
+		}
+	}
+	for _, m := range merges {
+		if err := sw.MergeCell(m[0], m[1]); err != nil {
+			return err
+		}
+	}
+
+	deltaCells := func(d pnl.Delta) []any {
+		cells := []any{d.Current, d.Previous, d.Change, nil}
+		if d.ChangePct != nil {
+			cells[3] = *d.ChangePct
+		}
+		return cells
+	}
+	for i := range max(len(comparison.Summary), len(comparison.SKUs)) {
+		row := make([]any, width)
+		if i < len(comparison.Summary) {
+			figure := comparison.Summary[i]
+			label := locale.T(summaryLabels[figure.Figure])
+			if figure.Figure == "tax" {
+				label = fmt.Sprintf(locale.T(i18n.SummaryTaxRate), result.Summary.TaxRate*100)
+			}
+			row[colCompareSummary] = label
+			copy(row[colCompareSummary+1:], deltaCells(figure.Delta))
+		}
+		if i < len(comparison.SKUs) {
+			sku := comparison.SKUs[i]
+			saName := sku.SaName
+			if saName == "" {
+				saName = locale.T(i18n.SKUGeneralCosts)
+			}
+			row[colCompareSKU], row[colCompareSKU+1] = saName, sku.TsName
+			for j, figure := range compareSKUFigures {
+				copy(row[colCompareSKU+2+j*len(deltaLabels):], deltaCells(figure.value(sku)))
+			}
+		}
+		if err := sw.SetRow(fmt.Sprintf("A%d", i+4), row); err != nil {
+			return fmt.Errorf("failed to write comparison row %d: %w", i+4, err)
+		}
 	}
-	return buf.Bytes(), nil
+	return sw.Flush()
 }
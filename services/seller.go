@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"omnituan.online/store"
+	"omnituan.online/wb"
+)
+
+// Seller is whose WB data is fetched: Key names its local cache and APIKey
+// authenticates with WB.
+type Seller struct {
+	Key    string
+	APIKey string
+}
+
+// SellerFromAPIKey identifies a seller known only by its token, as on the
+// command line. Its cache is keyed by a hash of the token.
+func SellerFromAPIKey(apiKey string) Seller {
+	return Seller{Key: store.SellerKey(apiKey), APIKey: apiKey}
+}
+
+// CheckToken decodes apiKey and returns wb.ErrMalformedToken,
+// wb.ErrTokenExpired or a *wb.ScopeError when it can't be used for calls
+// needing scopes, so such keys fail before any WB request.
+func CheckToken(apiKey string, need wb.Scope) error {
+	info, err := wb.ParseToken(apiKey)
+	if err != nil {
+		return err
+	}
+	return info.Check(need, time.Now())
+}
+
+// IsTokenError reports whether err comes from CheckToken.
+func IsTokenError(err error) bool {
+	var scopeErr *wb.ScopeError
+	return errors.Is(err, wb.ErrMalformedToken) || errors.Is(err, wb.ErrTokenExpired) || errors.As(err, &scopeErr)
+}
+
+// ValidateToken checks apiKey locally and then asks WB whether it is still
+// accepted.
+func ValidateToken(ctx context.Context, apiKey string) error {
+	if err := CheckToken(apiKey, 0); err != nil {
+		return err
+	}
+	if err := wbClient.Ping(ctx, apiKey); err != nil {
+		return upstreamError(ctx, err)
+	}
+	return nil
+}
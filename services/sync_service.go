@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"omnituan.online/logging"
+	"omnituan.online/models"
+	"omnituan.online/store"
+)
+
+// SyncLookback is how far back the first sync of an API key reaches.
+var SyncLookback = 90 * 24 * time.Hour
+
+type SyncResult struct {
+	Rows      int
+	LastRrdID int64
+	Since     time.Time
+}
+
+// SyncReports pulls the rows WB added since the last sync of seller into the
+// report store. Later reports over the synced range need no WB calls. The
+// first sync of a seller starts at since, or SyncLookback ago when it is zero;
+// a since before the range synced so far is backfilled first.
+func SyncReports(ctx context.Context, seller Seller, since time.Time) (SyncResult, error) {
+	if reportStore == nil {
+		return SyncResult{}, errors.New("report store is not configured")
+	}
+
+	state, err := reportStore.SyncState(seller.Key)
+	if err != nil {
+		return SyncResult{}, err
+	}
+
+	startedAt := time.Now()
+	var synced time.Time
+	if state.Since != "" {
+		if synced, err = time.Parse("2006-01-02", state.Since); err != nil {
+			return SyncResult{}, fmt.Errorf("invalid sync state: %w", err)
+		}
+	}
+	if since.IsZero() {
+		since = synced
+	}
+	if since.IsZero() {
+		since = startedAt.Add(-SyncLookback)
+	}
+	since = store.WeekStart(since)
+
+	result := SyncResult{Since: since, LastRrdID: state.LastRrdID}
+	if !synced.IsZero() {
+		if since.Before(synced) {
+			rows, err := backfillReports(ctx, seller, since, synced, startedAt)
+			result.Rows += rows
+			if err != nil {
+				return result, err
+			}
+		} else {
+			result.Since = synced
+		}
+		// The cursor covers the synced range only.
+		since = synced
+	}
+	state.Since = result.Since.Format("2006-01-02")
+
+	err = streamReportDetails(ctx, seller.APIKey, since, startedAt, state.LastRrdID, func(page []models.ReportDetails) error {
+		if err := reportStore.Put(seller.Key, page, nil, startedAt); err != nil {
+			return fmt.Errorf("failed to cache reports: %w", err)
+		}
+		// Lưu con trỏ sau mỗi trang để lần chạy sau tiếp tục từ đây
+		state.LastRrdID = page[len(page)-1].RrdID
+		result.Rows += len(page)
+		result.LastRrdID = state.LastRrdID
+		return reportStore.SetSyncState(seller.Key, state)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Every row of the range is stored now, so settled weeks count as cached.
+	if err := reportStore.Put(seller.Key, nil, store.Weeks(result.Since, startedAt), startedAt); err != nil {
+		return result, err
+	}
+	state.SyncedAt = startedAt
+	return result, reportStore.SetSyncState(seller.Key, state)
+}
+
+// backfillReports stores the rows of [from, to) for a seller already synced
+// from to. The rrdid cursor only moves forward, so they are read from the
+// start of the range.
+func backfillReports(ctx context.Context, seller Seller, from, to, fetchedAt time.Time) (int, error) {
+	end := to.AddDate(0, 0, -1)
+	rows := 0
+	err := streamReportDetails(ctx, seller.APIKey, from, end, 0, func(page []models.ReportDetails) error {
+		if err := reportStore.Put(seller.Key, page, nil, fetchedAt); err != nil {
+			return fmt.Errorf("failed to cache reports: %w", err)
+		}
+		rows += len(page)
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, reportStore.Put(seller.Key, nil, store.Weeks(from, end), fetchedAt)
+}
+
+// RunReportSync syncs the sellers returned by sellers right away and then
+// every interval until ctx is cancelled. The list is asked for on every tick
+// so sellers registered meanwhile are picked up. Failures are logged and
+// retried on the next tick.
+func RunReportSync(ctx context.Context, sellers func() []Seller, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, seller := range sellers() {
+			// Each run gets its own ID so its WB calls can be told apart.
+			syncCtx := logging.WithRequestID(ctx, "sync-"+logging.NewRequestID())
+			result, err := SyncReports(syncCtx, seller, time.Time{})
+			if errors.Is(err, ErrCancelled) {
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(syncCtx, "Sync failed", "seller", seller.Key, "error", err)
+				continue
+			}
+			slog.InfoContext(syncCtx, "Synced report rows", "seller", seller.Key, "rows", result.Rows, "last_rrdid", result.LastRrdID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"omnituan.online/store"
+	"omnituan.online/wb"
+	"omnituan.online/wb/wbfake"
+)
+
+func TestSyncReportsBackfillsEarlierSince(t *testing.T) {
+	fake := wbfake.NewServer()
+	defer fake.Close()
+	defer SetWBClient(wbClient)
+	SetWBClient(wb.NewClient(fake.Config()))
+
+	s, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetReportStore(reportStore)
+	SetReportStore(s)
+
+	ctx := context.Background()
+	seller := SellerFromAPIKey(wbfake.Token("sync-test", wb.ScopeStatistics, time.Now().Add(time.Hour)))
+	day := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02", s)
+		return t
+	}
+
+	// The fixture has 34 rows from Monday 2025-05-19 and 74 before it.
+	steps := []struct {
+		since time.Time
+		rows  int
+		from  string
+	}{
+		{day("2025-05-19"), 34, "2025-05-19"},
+		{day("2025-05-05"), 74, "2025-05-05"},
+		{time.Time{}, 0, "2025-05-05"},
+		{day("2025-05-12"), 0, "2025-05-05"},
+	}
+	for i, step := range steps {
+		result, err := SyncReports(ctx, seller, step.since)
+		if err != nil {
+			t.Fatalf("sync %d: %v", i, err)
+		}
+		if result.Rows != step.rows || result.Since.Format("2006-01-02") != step.from {
+			t.Errorf("sync %d: %d rows since %s, want %d since %s",
+				i, result.Rows, result.Since.Format("2006-01-02"), step.rows, step.from)
+		}
+	}
+
+	rows, err := s.Rows(seller.Key, day("2025-05-05"), day("2025-05-25"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(fake.Reports) {
+		t.Errorf("store holds %d rows, want %d", len(rows), len(fake.Reports))
+	}
+}
@@ -0,0 +1,11 @@
+package services
+
+import "omnituan.online/wb"
+
+var wbClient wb.Client = wb.NewClient(wb.Config{})
+
+// SetWBClient replaces the Wildberries client used by every service, e.g. to
+// point them at wbfake or a different API host.
+func SetWBClient(client wb.Client) {
+	wbClient = client
+}
@@ -0,0 +1,325 @@
+// Package store keeps realization report rows fetched from Wildberries on
+// disk, so repeated reports over the same period don't spend the
+// reportDetailByPeriod rate limit again.
+//
+// Layout under the root directory:
+//
+//	<seller>/index.json              covered weeks and per-report rr_dt range
+//	<seller>/reports/<report id>.json rows of one realization report, by rrd_id
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"omnituan.online/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Settle is how long after a week ends WB may still add rows to it. Weeks
+// fetched earlier than that are fetched again next time.
+const Settle = 7 * 24 * time.Hour
+
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// index is the per-seller metadata file.
+type index struct {
+	// Weeks maps the Monday of every final week to when it was fetched.
+	Weeks map[string]time.Time `json:"weeks"`
+	// Reports maps a realization report ID to the rr_dt range of its rows.
+	Reports map[string]reportSpan `json:"reports"`
+	Sync    SyncState             `json:"sync"`
+}
+
+// SyncState is where the incremental sync of a seller stopped.
+type SyncState struct {
+	// Since is the first day the sync covers.
+	Since string `json:"since,omitempty"`
+	// LastRrdID is the highest rrd_id stored so far; WB returns only newer
+	// rows when it is passed as the rrdid cursor.
+	LastRrdID int64     `json:"lastRrdId,omitempty"`
+	SyncedAt  time.Time `json:"syncedAt,omitempty"`
+}
+
+type reportSpan struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Rows int    `json:"rows"`
+}
+
+// Open returns a store rooted at dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Check reports whether the store directory can still be written to.
+func (s *Store) Check() error {
+	f, err := os.CreateTemp(s.dir, ".check-*")
+	if err != nil {
+		return fmt.Errorf("store is not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// SellerKey derives the directory name of a seller from its API key, so
+// keys never end up on disk.
+func SellerKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WeekStart returns the Monday of the week holding t.
+func WeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Weeks lists the Mondays of every week overlapping [from, to].
+func Weeks(from, to time.Time) []time.Time {
+	var weeks []time.Time
+	for w := WeekStart(from); !w.After(to); w = w.AddDate(0, 0, 7) {
+		weeks = append(weeks, w)
+	}
+	return weeks
+}
+
+// Missing returns the weeks of [from, to] not yet stored as final.
+func (s *Store) Missing(seller string, from, to time.Time) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(seller)
+	if err != nil {
+		return nil, err
+	}
+	var missing []time.Time
+	for _, w := range Weeks(from, to) {
+		if _, ok := idx.Weeks[w.Format(dateLayout)]; !ok {
+			missing = append(missing, w)
+		}
+	}
+	return missing, nil
+}
+
+// Put merges rows into the seller's reports, replacing rows with the same
+// rrd_id, and marks the given weeks as covered once they have settled.
+func (s *Store) Put(seller string, rows []models.ReportDetails, weeks []time.Time, fetchedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(seller)
+	if err != nil {
+		return err
+	}
+
+	byReport := map[int64][]models.ReportDetails{}
+	for _, r := range rows {
+		byReport[r.RealizationReportID] = append(byReport[r.RealizationReportID], r)
+	}
+	for id, fresh := range byReport {
+		merged, err := s.mergeReport(seller, id, fresh)
+		if err != nil {
+			return err
+		}
+		idx.Reports[strconv.FormatInt(id, 10)] = spanOf(merged)
+	}
+
+	for _, w := range weeks {
+		if fetchedAt.Sub(w.AddDate(0, 0, 7)) >= Settle {
+			idx.Weeks[w.Format(dateLayout)] = fetchedAt
+		}
+	}
+	return s.saveIndex(seller, idx)
+}
+
+// Rows returns the stored rows whose rr_dt falls within [from, to], in
+// rrd_id order.
+func (s *Store) Rows(seller string, from, to time.Time) ([]models.ReportDetails, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(seller)
+	if err != nil {
+		return nil, err
+	}
+	fromDay, toDay := from.Format(dateLayout), to.Format(dateLayout)
+
+	var rows []models.ReportDetails
+	for id, span := range idx.Reports {
+		if span.To < fromDay || span.From > toDay {
+			continue
+		}
+		report, err := s.readReport(seller, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range report {
+			if day := rrDay(r); day >= fromDay && day <= toDay {
+				rows = append(rows, r)
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].RrdID < rows[j].RrdID })
+	return rows, nil
+}
+
+func (s *Store) SyncState(seller string) (SyncState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(seller)
+	return idx.Sync, err
+}
+
+func (s *Store) SetSyncState(seller string, state SyncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := s.loadIndex(seller)
+	if err != nil {
+		return err
+	}
+	idx.Sync = state
+	return s.saveIndex(seller, idx)
+}
+
+func (s *Store) mergeReport(seller string, id int64, fresh []models.ReportDetails) ([]models.ReportDetails, error) {
+	name := strconv.FormatInt(id, 10)
+	existing, err := s.readReport(seller, name)
+	if err != nil {
+		return nil, err
+	}
+
+	byRrdID := make(map[int64]models.ReportDetails, len(existing)+len(fresh))
+	for _, r := range existing {
+		byRrdID[r.RrdID] = r
+	}
+	for _, r := range fresh {
+		byRrdID[r.RrdID] = r
+	}
+	merged := make([]models.ReportDetails, 0, len(byRrdID))
+	for _, r := range byRrdID {
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].RrdID < merged[j].RrdID })
+
+	if err := writeJSON(s.reportPath(seller, name), merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+func (s *Store) readReport(seller, id string) ([]models.ReportDetails, error) {
+	var rows []models.ReportDetails
+	if err := readJSON(s.reportPath(seller, id), &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *Store) loadIndex(seller string) (index, error) {
+	idx := index{Weeks: map[string]time.Time{}, Reports: map[string]reportSpan{}}
+	if err := readJSON(filepath.Join(s.dir, seller, "index.json"), &idx); err != nil {
+		return idx, err
+	}
+	if idx.Weeks == nil {
+		idx.Weeks = map[string]time.Time{}
+	}
+	if idx.Reports == nil {
+		idx.Reports = map[string]reportSpan{}
+	}
+	return idx, nil
+}
+
+func (s *Store) saveIndex(seller string, idx index) error {
+	return writeJSON(filepath.Join(s.dir, seller, "index.json"), idx)
+}
+
+func (s *Store) reportPath(seller, id string) string {
+	return filepath.Join(s.dir, seller, "reports", id+".json")
+}
+
+func spanOf(rows []models.ReportDetails) reportSpan {
+	span := reportSpan{Rows: len(rows)}
+	for _, r := range rows {
+		day := rrDay(r)
+		if span.From == "" || day < span.From {
+			span.From = day
+		}
+		if day > span.To {
+			span.To = day
+		}
+	}
+	return span
+}
+
+// rrDay is the date part of rr_dt, which WB sends with or without a time.
+func rrDay(r models.ReportDetails) string {
+	if len(r.RrDt) > len(dateLayout) {
+		return r.RrDt[:len(dateLayout)]
+	}
+	return r.RrDt
+}
+
+// readJSON decodes path into v, leaving v untouched when the file does not
+// exist yet.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSON replaces path atomically so a crash never leaves half a file.
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
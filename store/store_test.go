@@ -0,0 +1,142 @@
+package store
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"omnituan.online/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func openTest(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func missing(t *testing.T, s *Store, from, to string) []string {
+	t.Helper()
+	weeks, err := s.Missing("seller", day(from), day(to))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []string
+	for _, w := range weeks {
+		days = append(days, w.Format(dateLayout))
+	}
+	return days
+}
+
+func TestMissingEmpty(t *testing.T) {
+	s := openTest(t)
+	// 2025-05-07 is a Wednesday, so its Monday comes first.
+	want := []string{"2025-05-05", "2025-05-12", "2025-05-19"}
+	if got := missing(t, s, "2025-05-07", "2025-05-25"); !slices.Equal(got, want) {
+		t.Errorf("Missing = %v, want %v", got, want)
+	}
+}
+
+func TestPutRows(t *testing.T) {
+	s := openTest(t)
+	rows := []models.ReportDetails{
+		{RrdID: 3, RealizationReportID: 10, RrDt: "2025-05-06"},
+		{RrdID: 1, RealizationReportID: 10, RrDt: "2025-05-05T10:00:00"},
+		{RrdID: 2, RealizationReportID: 11, RrDt: "2025-05-13"},
+		{RrdID: 4, RealizationReportID: 11, RrDt: "2025-05-20"},
+	}
+	if err := s.Put("seller", rows, nil, day("2025-06-01")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     []int64
+	}{
+		{"2025-05-05", "2025-05-25", []int64{1, 2, 3, 4}},
+		{"2025-05-06", "2025-05-13", []int64{2, 3}},
+		{"2025-05-05", "2025-05-05", []int64{1}},
+		{"2025-05-21", "2025-05-31", nil},
+	}
+	for _, tt := range tests {
+		got, err := s.Rows("seller", day(tt.from), day(tt.to))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, r := range got {
+			ids = append(ids, r.RrdID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("Rows(%s, %s) = %v, want %v", tt.from, tt.to, ids, tt.want)
+		}
+	}
+
+	if other, err := s.Rows("other", day("2025-05-05"), day("2025-05-25")); err != nil || len(other) != 0 {
+		t.Errorf("another seller has %d rows, %v", len(other), err)
+	}
+}
+
+func TestPutSettleWindow(t *testing.T) {
+	s := openTest(t)
+	weeks := Weeks(day("2025-05-05"), day("2025-05-25"))
+	// The week of 2025-05-05 ended ten days before, the one of 2025-05-12
+	// three days before, and the last has not ended yet.
+	if err := s.Put("seller", nil, weeks, day("2025-05-22")); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2025-05-12", "2025-05-19"}
+	if got := missing(t, s, "2025-05-05", "2025-05-25"); !slices.Equal(got, want) {
+		t.Errorf("Missing = %v, want %v", got, want)
+	}
+
+	// Exactly Settle after its end the week of 2025-05-12 is final.
+	if err := s.Put("seller", nil, weeks, day("2025-05-26")); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"2025-05-19"}
+	if got := missing(t, s, "2025-05-05", "2025-05-25"); !slices.Equal(got, want) {
+		t.Errorf("Missing = %v, want %v", got, want)
+	}
+}
+
+func TestPutDeduplicatesRrdID(t *testing.T) {
+	s := openTest(t)
+	first := []models.ReportDetails{
+		{RrdID: 1, RealizationReportID: 10, RrDt: "2025-05-05", RetailAmount: 100},
+		{RrdID: 2, RealizationReportID: 10, RrDt: "2025-05-06", RetailAmount: 200},
+	}
+	overlap := []models.ReportDetails{
+		{RrdID: 2, RealizationReportID: 10, RrDt: "2025-05-06", RetailAmount: 250},
+		{RrdID: 3, RealizationReportID: 10, RrDt: "2025-05-07", RetailAmount: 300},
+	}
+	for _, rows := range [][]models.ReportDetails{first, overlap, overlap} {
+		if err := s.Put("seller", rows, nil, day("2025-05-08")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := s.Rows("seller", day("2025-05-05"), day("2025-05-11"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{100, 250, 300}
+	if len(rows) != len(want) {
+		t.Fatalf("%d rows, want %d", len(rows), len(want))
+	}
+	for i, r := range rows {
+		if r.RrdID != int64(i+1) || r.RetailAmount != want[i] {
+			t.Errorf("row %d = rrd_id %d, retail %v; want %d, %v", i, r.RrdID, r.RetailAmount, i+1, want[i])
+		}
+	}
+}
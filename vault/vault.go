@@ -0,0 +1,269 @@
+// Package vault stores the WB API tokens of registered sellers encrypted at
+// rest, so clients refer to a seller ID instead of sending the token.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("seller not found")
+
+// KeySize is the length of the AES-256 key sealing the tokens.
+const KeySize = 32
+
+// Seller is the public view of a registered seller; it never carries the
+// token itself.
+type Seller struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TokenHint string    `json:"tokenHint"`
+	CreatedAt time.Time `json:"createdAt"`
+	RotatedAt time.Time `json:"rotatedAt"`
+}
+
+// record is a seller as written to disk.
+type record struct {
+	Seller
+	Nonce  []byte `json:"nonce"`
+	Sealed []byte `json:"sealed"`
+}
+
+type Vault struct {
+	path string
+	aead cipher.AEAD
+
+	mu      sync.RWMutex
+	records map[string]record
+}
+
+// ParseKey decodes a base64 AES-256 key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("vault key is not base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("vault key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// Open loads the vault file at path, which need not exist yet.
+func Open(path string, key []byte) (*Vault, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{path: path, aead: aead, records: map[string]record{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode vault: %w", err)
+	}
+	for _, r := range records {
+		v.records[r.ID] = r
+	}
+
+	// Fail at startup rather than on the first request when the key is wrong.
+	for _, r := range records {
+		if _, err := v.open(r); err != nil {
+			return nil, fmt.Errorf("cannot decrypt seller %s, wrong vault key? %w", r.ID, err)
+		}
+	}
+	return v, nil
+}
+
+// Add registers a seller and returns it with its new ID.
+func (v *Vault) Add(name, token string) (Seller, error) {
+	id, err := newID()
+	if err != nil {
+		return Seller{}, err
+	}
+	now := time.Now().UTC()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	seller := Seller{ID: id, Name: name, CreatedAt: now}
+	if err := v.seal(seller, token, now); err != nil {
+		return Seller{}, err
+	}
+	return v.records[id].Seller, nil
+}
+
+// Rotate replaces the token of a seller.
+func (v *Vault) Rotate(id, token string) (Seller, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	r, ok := v.records[id]
+	if !ok {
+		return Seller{}, ErrNotFound
+	}
+	if err := v.seal(r.Seller, token, time.Now().UTC()); err != nil {
+		return Seller{}, err
+	}
+	return v.records[id].Seller, nil
+}
+
+func (v *Vault) Delete(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	r, ok := v.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(v.records, id)
+	if err := v.save(); err != nil {
+		v.records[id] = r
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) Get(id string) (Seller, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	r, ok := v.records[id]
+	if !ok {
+		return Seller{}, ErrNotFound
+	}
+	return r.Seller, nil
+}
+
+// List returns every seller, oldest first.
+func (v *Vault) List() []Seller {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	sellers := make([]Seller, 0, len(v.records))
+	for _, r := range v.records {
+		sellers = append(sellers, r.Seller)
+	}
+	sort.Slice(sellers, func(i, j int) bool { return sellers[i].CreatedAt.Before(sellers[j].CreatedAt) })
+	return sellers
+}
+
+// Token decrypts the WB token of a seller.
+func (v *Vault) Token(id string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	r, ok := v.records[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v.open(r)
+}
+
+// seal encrypts token for seller and persists the vault. The seller ID is
+// authenticated with the ciphertext so sealed tokens can't be swapped
+// between sellers. Callers hold mu.
+func (v *Vault) seal(seller Seller, token string, now time.Time) error {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	seller.TokenHint = hint(token)
+	seller.RotatedAt = now
+
+	previous, existed := v.records[seller.ID]
+	v.records[seller.ID] = record{
+		Seller: seller,
+		Nonce:  nonce,
+		Sealed: v.aead.Seal(nil, nonce, []byte(token), []byte(seller.ID)),
+	}
+	if err := v.save(); err != nil {
+		if existed {
+			v.records[seller.ID] = previous
+		} else {
+			delete(v.records, seller.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) open(r record) (string, error) {
+	token, err := v.aead.Open(nil, r.Nonce, r.Sealed, []byte(r.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token of seller %s: %w", r.ID, err)
+	}
+	return string(token), nil
+}
+
+// save rewrites the vault file atomically. Callers hold mu.
+func (v *Vault) save() error {
+	records := make([]record, 0, len(v.records))
+	for _, r := range v.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), ".vault-*")
+	if err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), v.path); err != nil {
+		return fmt.Errorf("failed to save vault: %w", err)
+	}
+	return nil
+}
+
+// hint keeps the last characters of a token so sellers can tell which one
+// is stored.
+func hint(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate seller id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
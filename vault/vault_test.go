@@ -0,0 +1,162 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{7}, KeySize)
+
+const testToken = "wb-token-0123456789abcdef"
+
+func openTest(t *testing.T) (*Vault, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sellers.json")
+	v, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, path
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	v, path := openTest(t)
+	seller, err := v.Add("shop", testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seller.TokenHint != "****cdef" {
+		t.Errorf("hint = %q", seller.TokenHint)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(testToken)) {
+		t.Error("vault file holds the token in plain text")
+	}
+
+	reopened, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := reopened.Token(seller.ID)
+	if err != nil || token != testToken {
+		t.Errorf("Token = %q, %v; want %q", token, err, testToken)
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	v, path := openTest(t)
+	if _, err := v.Add("shop", testToken); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Open(path, bytes.Repeat([]byte{8}, KeySize))
+	if err == nil || !strings.Contains(err.Error(), "wrong vault key") {
+		t.Errorf("Open with another key = %v, want a decryption error", err)
+	}
+}
+
+// The seller ID is the associated data of the seal, so a ciphertext copied
+// under another seller does not decrypt.
+func TestOpenMovedCiphertext(t *testing.T) {
+	v, path := openTest(t)
+	victim, err := v.Add("victim", testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacker, err := v.Add("attacker", "wb-token-attacker-000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]*record{}
+	for i := range records {
+		byID[records[i].ID] = &records[i]
+	}
+	byID[attacker.ID].Nonce = byID[victim.ID].Nonce
+	byID[attacker.ID].Sealed = byID[victim.ID].Sealed
+	data, err = json.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path, testKey); err == nil || !strings.Contains(err.Error(), attacker.ID) {
+		t.Errorf("Open = %v, want seller %s to fail", err, attacker.ID)
+	}
+}
+
+// breakWrites points v below a regular file, where no write can succeed,
+// and returns a func restoring the path. A read-only directory would not
+// stop root.
+func breakWrites(t *testing.T, v *Vault) (restore func()) {
+	t.Helper()
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := v.path
+	v.path = filepath.Join(blocker, "sellers.json")
+	return func() { v.path = path }
+}
+
+func TestFailedWriteKeepsVault(t *testing.T) {
+	v, path := openTest(t)
+	seller, err := v.Add("shop", testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restore := breakWrites(t, v)
+	if _, err := v.Rotate(seller.ID, "wb-token-rotated-0000000"); err == nil {
+		t.Error("Rotate succeeded without saving")
+	}
+	if err := v.Delete(seller.ID); err == nil {
+		t.Error("Delete succeeded without saving")
+	}
+	restore()
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("vault file changed by failed writes")
+	}
+	if token, err := v.Token(seller.ID); err != nil || token != testToken {
+		t.Errorf("Token = %q, %v; want the token before the failed rotation", token, err)
+	}
+
+	// The next successful save must not carry the rolled back changes.
+	if _, err := v.Add("other", "wb-token-other-000000000"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, err := reopened.Token(seller.ID); err != nil || token != testToken {
+		t.Errorf("reopened Token = %q, %v; want %q", token, err, testToken)
+	}
+}
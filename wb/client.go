@@ -0,0 +1,288 @@
+package wb
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"omnituan.online/models"
+)
+
+const (
+	DefaultStatisticsURL = "https://statistics-api.wildberries.ru"
+	DefaultAnalyticsURL  = "https://seller-analytics-api.wildberries.ru"
+
+	DefaultAnalyticsTimeout = 10 * time.Second
+)
+
+const (
+	reportDetailByPeriodPath = "/api/v5/supplier/reportDetailByPeriod"
+	nmReportDetailPath       = "/api/v2/nm-report/detail"
+	pingPath                 = "/ping"
+)
+
+// Client is the subset of the Wildberries seller API used by the services.
+type Client interface {
+	ReportDetailByPeriod(ctx context.Context, apiKey string, dateFrom, dateTo time.Time, limit int, rrdid int64) ([]models.ReportDetails, error)
+	NMReportDetail(ctx context.Context, apiKey string, payload models.AnalyticOrderRequest) (models.AnalyticOrderResponse, error)
+	// Ping checks that apiKey is accepted by the statistics API.
+	Ping(ctx context.Context, apiKey string) error
+}
+
+// Config controls where and how HTTPClient talks to Wildberries.
+// Zero values fall back to the production endpoints.
+type Config struct {
+	StatisticsURL string
+	AnalyticsURL  string
+
+	// StatisticsTimeout is zero by default: reportDetailByPeriod pages can
+	// take minutes to arrive for large sellers.
+	StatisticsTimeout time.Duration
+	AnalyticsTimeout  time.Duration
+
+	// Zero policies fall back to DefaultStatisticsRetry and
+	// DefaultAnalyticsRetry.
+	StatisticsRetry RetryPolicy
+	AnalyticsRetry  RetryPolicy
+
+	Transport http.RoundTripper
+}
+
+// ErrDecode is wrapped by errors for responses that are not the expected JSON.
+var ErrDecode = errors.New("failed to decode JSON")
+
+// StatusError is returned when Wildberries answers with a non-200 status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the wait WB requested via Retry-After or X-Ratelimit-*.
+	RetryAfter time.Duration
+	// RequestID and Detail come from WB's JSON error body when it has one;
+	// WB support asks for the request ID.
+	RequestID string
+	Detail    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error response: status code %d, body: %s", e.StatusCode, e.Body)
+}
+
+type HTTPClient struct {
+	statisticsURL   string
+	analyticsURL    string
+	statistics      *http.Client
+	analytics       *http.Client
+	statisticsRetry RetryPolicy
+	analyticsRetry  RetryPolicy
+}
+
+func NewClient(cfg Config) *HTTPClient {
+	if cfg.StatisticsURL == "" {
+		cfg.StatisticsURL = DefaultStatisticsURL
+	}
+	if cfg.AnalyticsURL == "" {
+		cfg.AnalyticsURL = DefaultAnalyticsURL
+	}
+	if cfg.AnalyticsTimeout == 0 {
+		cfg.AnalyticsTimeout = DefaultAnalyticsTimeout
+	}
+	if cfg.StatisticsRetry == (RetryPolicy{}) {
+		cfg.StatisticsRetry = DefaultStatisticsRetry
+	}
+	if cfg.AnalyticsRetry == (RetryPolicy{}) {
+		cfg.AnalyticsRetry = DefaultAnalyticsRetry
+	}
+
+	return &HTTPClient{
+		statisticsURL:   strings.TrimRight(cfg.StatisticsURL, "/"),
+		analyticsURL:    strings.TrimRight(cfg.AnalyticsURL, "/"),
+		statistics:      &http.Client{Timeout: cfg.StatisticsTimeout, Transport: cfg.Transport},
+		analytics:       &http.Client{Timeout: cfg.AnalyticsTimeout, Transport: cfg.Transport},
+		statisticsRetry: cfg.StatisticsRetry,
+		analyticsRetry:  cfg.AnalyticsRetry,
+	}
+}
+
+func (c *HTTPClient) ReportDetailByPeriod(ctx context.Context, apiKey string, dateFrom, dateTo time.Time, limit int, rrdid int64) ([]models.ReportDetails, error) {
+	query := url.Values{}
+	query.Set("dateFrom", dateFrom.Format("2006-01-02"))
+	query.Set("dateTo", dateTo.Format("2006-01-02"))
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("rrdid", strconv.FormatInt(rrdid, 10))
+
+	var reports []models.ReportDetails
+	err := c.do(ctx, c.statistics, c.statisticsRetry, apiKey, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", c.statisticsURL+reportDetailByPeriodPath+"?"+query.Encode(), nil)
+	}, func(body []byte) (int, error) {
+		// WB answers 204 with an empty body once the cursor is exhausted.
+		if len(body) == 0 {
+			return 0, nil
+		}
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrDecode, err)
+		}
+		return len(reports), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (c *HTTPClient) NMReportDetail(ctx context.Context, apiKey string, payload models.AnalyticOrderRequest) (models.AnalyticOrderResponse, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return models.AnalyticOrderResponse{}, err
+	}
+
+	var response models.AnalyticOrderResponse
+	err = c.do(ctx, c.analytics, c.analyticsRetry, apiKey, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "POST", c.analyticsURL+nmReportDetailPath, bytes.NewReader(payloadBytes))
+	}, func(body []byte) (int, error) {
+		if err := json.Unmarshal(body, &response); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrDecode, err)
+		}
+		return len(response.Data.Cards), nil
+	})
+	if err != nil {
+		return models.AnalyticOrderResponse{}, err
+	}
+	return response, nil
+}
+
+// Ping is not retried: it answers whether the token works right now. It
+// goes through the analytics client for its bounded timeout.
+func (c *HTTPClient) Ping(ctx context.Context, apiKey string) error {
+	return c.do(ctx, c.analytics, RetryPolicy{MaxAttempts: 1}, apiKey, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", c.statisticsURL+pingPath, nil)
+	}, func([]byte) (int, error) { return 0, nil })
+}
+
+// do sends the request built by newRequest, retrying according to policy,
+// and hands the response body to decode, which returns the number of rows
+// it held. newRequest is called once per attempt so request bodies can be
+// replayed. Every attempt is logged.
+func (c *HTTPClient) do(ctx context.Context, client *http.Client, policy RetryPolicy, apiKey string, newRequest func() (*http.Request, error), decode func(body []byte) (int, error)) error {
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return fmt.Errorf("failed to create request: %v", err)
+		}
+
+		sent := time.Now()
+		status, body, err := c.send(client, req, apiKey)
+		latency := time.Since(sent)
+		endpoint := req.URL.Path
+		requestDuration.Observe(latency.Seconds(), endpoint)
+		if status != 0 {
+			requestsTotal.Inc(endpoint, strconv.Itoa(status))
+		} else {
+			requestsTotal.Inc(endpoint, "error")
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.Redacted()),
+			slog.String("token", redactToken(apiKey)),
+			slog.Int("attempt", attempt),
+			slog.Duration("latency", latency),
+		}
+		if status != 0 {
+			attrs = append(attrs, slog.Int("status", status))
+		}
+		if err == nil {
+			rows, err := decode(body)
+			if err != nil {
+				slog.LogAttrs(ctx, slog.LevelWarn, "WB call failed", append(attrs, slog.Any("error", err))...)
+				return err
+			}
+			slog.LogAttrs(ctx, slog.LevelInfo, "WB call", append(attrs, slog.Int("rows", rows), slog.Int("bytes", len(body)))...)
+			return nil
+		}
+		attrs = append(attrs, slog.Any("error", err))
+
+		if !retryable(ctx, err) || attempt >= policy.MaxAttempts {
+			slog.LogAttrs(ctx, slog.LevelWarn, "WB call failed", attrs...)
+			return err
+		}
+
+		wait := policy.backoff(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			wait = statusErr.RetryAfter
+		}
+		if policy.MaxElapsed > 0 && time.Since(start)+wait > policy.MaxElapsed {
+			slog.LogAttrs(ctx, slog.LevelWarn, "WB call failed", attrs...)
+			return err
+		}
+
+		if status == http.StatusTooManyRequests {
+			rateLimitWait.Observe(wait.Seconds(), endpoint)
+		}
+		slog.LogAttrs(ctx, slog.LevelWarn, "WB call failed, retrying", append(attrs, slog.Duration("retry_in", wait))...)
+		if err := Sleep(ctx, wait); err != nil {
+			return fmt.Errorf("failed to make request: %w", err)
+		}
+	}
+}
+
+func (c *HTTPClient) send(client *http.Client, req *http.Request, apiKey string) (status int, body []byte, err error) {
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.StatusCode, body, nil
+	case http.StatusNoContent:
+		return res.StatusCode, nil, nil
+	default:
+		statusErr := &StatusError{
+			StatusCode: res.StatusCode,
+			Body:       string(body),
+			RetryAfter: retryAfter(res.Header, time.Now()),
+			RequestID:  res.Header.Get("X-Request-Id"),
+		}
+		var wbErr struct {
+			Title     string `json:"title"`
+			Detail    string `json:"detail"`
+			RequestID string `json:"requestId"`
+		}
+		if json.Unmarshal(body, &wbErr) == nil {
+			statusErr.Detail = cmp.Or(wbErr.Detail, wbErr.Title)
+			statusErr.RequestID = cmp.Or(wbErr.RequestID, statusErr.RequestID)
+		}
+		return res.StatusCode, nil, statusErr
+	}
+}
+
+// redactToken keeps the last characters of a token so log lines of a
+// seller can be told apart without exposing it.
+func redactToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
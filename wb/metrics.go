@@ -0,0 +1,15 @@
+package wb
+
+import "omnituan.online/metrics"
+
+var (
+	requestsTotal = metrics.NewCounter("berrio_wb_requests_total",
+		"WB API calls by endpoint and HTTP status; status is \"error\" when no response arrived.",
+		"endpoint", "status")
+	requestDuration = metrics.NewHistogram("berrio_wb_request_duration_seconds",
+		"Latency of WB API calls, retries counted separately.",
+		[]float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300}, "endpoint")
+	rateLimitWait = metrics.NewHistogram("berrio_wb_rate_limit_wait_seconds",
+		"Waits before retrying a call WB answered with 429.",
+		[]float64{1, 2, 5, 10, 20, 30, 60, 120}, "endpoint")
+)
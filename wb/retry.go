@@ -0,0 +1,112 @@
+package wb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy bounds how hard the client retries one WB call. Throttled
+// (429) and 5xx responses, timeouts and dropped connections are retried;
+// anything else is returned immediately.
+type RetryPolicy struct {
+	// MaxAttempts counts the first try; 1 disables retries.
+	MaxAttempts int
+	// MaxElapsed caps the wall time spent on one call including waits.
+	MaxElapsed time.Duration
+	// BaseDelay and MaxDelay bound the jittered exponential backoff used
+	// when WB does not say how long to wait.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var (
+	// reportDetailByPeriod allows one request per minute, so waits are long.
+	DefaultStatisticsRetry = RetryPolicy{
+		MaxAttempts: 10,
+		MaxElapsed:  15 * time.Minute,
+		BaseDelay:   5 * time.Second,
+		MaxDelay:    1 * time.Minute,
+	}
+	DefaultAnalyticsRetry = RetryPolicy{
+		MaxAttempts: 5,
+		MaxElapsed:  3 * time.Minute,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+)
+
+// backoff returns the jittered delay before attempt+1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Equal jitter: half fixed, half random.
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	// Only transport failures that a second try can fix; a bad URL, TLS or
+	// DNS error fails the same way again.
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter reads how long WB asked us to wait. Retry-After may be seconds
+// or an HTTP date; the X-Ratelimit headers are always seconds.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	for _, name := range []string{"Retry-After", "X-Ratelimit-Retry", "X-Ratelimit-Reset"} {
+		value := header.Get(name)
+		if value == "" {
+			continue
+		}
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		if at, err := http.ParseTime(value); err == nil && at.After(now) {
+			return at.Sub(now)
+		}
+	}
+	return 0
+}
+
+// Sleep waits for d and returns ctx.Err() if ctx ends first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
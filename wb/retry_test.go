@@ -0,0 +1,114 @@
+package wb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 5, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   time.Duration
+	}{
+		{"none", nil, 0},
+		{"seconds", map[string]string{"Retry-After": "30"}, 30 * time.Second},
+		{"fractional seconds", map[string]string{"Retry-After": "1.5"}, 1500 * time.Millisecond},
+		{"HTTP date", map[string]string{"Retry-After": now.Add(90 * time.Second).Format(http.TimeFormat)}, 90 * time.Second},
+		{"HTTP date passed", map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat)}, 0},
+		{"X-Ratelimit-Retry", map[string]string{"X-Ratelimit-Retry": "12"}, 12 * time.Second},
+		{"X-Ratelimit-Reset", map[string]string{"X-Ratelimit-Reset": "7"}, 7 * time.Second},
+		{"Retry-After first", map[string]string{"Retry-After": "3", "X-Ratelimit-Retry": "12"}, 3 * time.Second},
+		{"garbage falls through", map[string]string{"Retry-After": "soon", "X-Ratelimit-Retry": "12"}, 12 * time.Second},
+		{"zero", map[string]string{"Retry-After": "0"}, 0},
+		{"negative", map[string]string{"X-Ratelimit-Retry": "-5"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			if got := retryAfter(header, now); got != tt.want {
+				t.Errorf("retryAfter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 8 * time.Second}
+	// Delays double from BaseDelay up to MaxDelay; equal jitter keeps each
+	// wait between half the delay and the delay.
+	for attempt, delay := range []time.Duration{1, 2, 4, 8, 8, 8, 8} {
+		delay *= time.Second
+		for range 200 {
+			got := p.backoff(attempt + 1)
+			if got < delay/2 || got > delay {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt+1, got, delay/2, delay)
+			}
+		}
+	}
+	// A large attempt must not overflow past the cap.
+	if got := p.backoff(100); got < 4*time.Second || got > 8*time.Second {
+		t.Errorf("backoff(100) = %v", got)
+	}
+	if got := (RetryPolicy{}).backoff(3); got != 0 {
+		t.Errorf("backoff without delays = %v, want 0", got)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	transport := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://statistics-api.wildberries.ru", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"429", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"500", &StatusError{StatusCode: http.StatusInternalServerError}, true},
+		{"503 wrapped", fmt.Errorf("page 3: %w", &StatusError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"timeout", transport(&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}), true},
+		{"connection reset", transport(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"unexpected EOF", transport(io.ErrUnexpectedEOF), true},
+		{"400", &StatusError{StatusCode: http.StatusBadRequest}, false},
+		{"401", &StatusError{StatusCode: http.StatusUnauthorized}, false},
+		{"404", &StatusError{StatusCode: http.StatusNotFound}, false},
+		{"DNS", transport(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "wb.invalid", IsNotFound: true}}), false},
+		{"connection refused", transport(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), false},
+		{"other", errors.New("decode: invalid character"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(context.Background(), tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, err := range []error{
+			&StatusError{StatusCode: http.StatusTooManyRequests},
+			transport(context.Canceled),
+			transport(io.ErrUnexpectedEOF),
+		} {
+			if retryable(ctx, err) {
+				t.Errorf("retryable(%v) after cancel = true", err)
+			}
+		}
+	})
+}
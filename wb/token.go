@@ -0,0 +1,135 @@
+package wb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken = errors.New("not a WB API token")
+	ErrTokenExpired   = errors.New("WB API token has expired")
+)
+
+// Scope is the set of API categories a token grants, the bit mask in its
+// "s" claim.
+type Scope uint64
+
+const (
+	ScopeContent     Scope = 1 << 1
+	ScopeAnalytics   Scope = 1 << 2
+	ScopePrices      Scope = 1 << 3
+	ScopeMarketplace Scope = 1 << 4
+	ScopeStatistics  Scope = 1 << 5
+	ScopePromotion   Scope = 1 << 6
+	ScopeFeedbacks   Scope = 1 << 7
+	ScopeChat        Scope = 1 << 9
+	ScopeSupplies    Scope = 1 << 10
+	ScopeReturns     Scope = 1 << 11
+	ScopeDocuments   Scope = 1 << 12
+	// ScopeReadOnly marks a token that may only call GET methods.
+	ScopeReadOnly Scope = 1 << 30
+)
+
+var scopeNames = []struct {
+	scope Scope
+	name  string
+}{
+	{ScopeContent, "content"},
+	{ScopeAnalytics, "analytics"},
+	{ScopePrices, "prices"},
+	{ScopeMarketplace, "marketplace"},
+	{ScopeStatistics, "statistics"},
+	{ScopePromotion, "promotion"},
+	{ScopeFeedbacks, "feedbacks"},
+	{ScopeChat, "chat"},
+	{ScopeSupplies, "supplies"},
+	{ScopeReturns, "returns"},
+	{ScopeDocuments, "documents"},
+	{ScopeReadOnly, "readonly"},
+}
+
+func (s Scope) Has(need Scope) bool {
+	return s&need == need
+}
+
+// Names lists the known categories in s.
+func (s Scope) Names() []string {
+	names := []string{}
+	for _, n := range scopeNames {
+		if s.Has(n.scope) {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+func (s Scope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Names())
+}
+
+// ScopeError is returned when a token lacks categories a call needs.
+type ScopeError struct {
+	Missing Scope
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("WB API token lacks the %s scope", strings.Join(e.Missing.Names(), ", "))
+}
+
+// TokenInfo is what a WB API token says about itself.
+type TokenInfo struct {
+	ID string `json:"id"`
+	// SellerID is the WB supplier the token belongs to.
+	SellerID  int64     `json:"sellerId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Scopes    Scope     `json:"scopes" swaggertype:"array,string"`
+	// Test tokens only work against the WB sandbox.
+	Test bool `json:"test"`
+}
+
+// ParseToken decodes the claims of a WB API token. The signature can only
+// be checked by WB, so a decoded token may still be revoked.
+func ParseToken(token string) (TokenInfo, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return TokenInfo{}, ErrMalformedToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return TokenInfo{}, ErrMalformedToken
+	}
+
+	var claims struct {
+		ID       string `json:"id"`
+		Expires  int64  `json:"exp"`
+		SellerID int64  `json:"oid"`
+		Scopes   Scope  `json:"s"`
+		Test     bool   `json:"t"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Expires == 0 {
+		return TokenInfo{}, ErrMalformedToken
+	}
+	return TokenInfo{
+		ID:        claims.ID,
+		SellerID:  claims.SellerID,
+		ExpiresAt: time.Unix(claims.Expires, 0).UTC(),
+		Scopes:    claims.Scopes,
+		Test:      claims.Test,
+	}, nil
+}
+
+// Check returns ErrTokenExpired or a *ScopeError when the token can't be
+// used for calls needing scopes.
+func (t TokenInfo) Check(need Scope, now time.Time) error {
+	if !now.Before(t.ExpiresAt) {
+		return fmt.Errorf("%w on %s", ErrTokenExpired, t.ExpiresAt.Format("2006-01-02"))
+	}
+	if missing := need &^ t.Scopes; missing != 0 {
+		return &ScopeError{Missing: missing}
+	}
+	return nil
+}
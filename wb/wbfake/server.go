@@ -0,0 +1,190 @@
+// Package wbfake runs an in-process stand-in for the Wildberries seller API
+// backed by recorded payloads, so the report and orders flows can be
+// exercised without network access or a real token.
+package wbfake
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"omnituan.online/models"
+	"omnituan.online/wb"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// RevokedTokenID marks tokens the server rejects with 401, like a token the
+// seller revoked in the WB portal.
+const RevokedTokenID = "revoked"
+
+// DefaultCardsPerPage is deliberately small so the recorded cards span
+// several nm-report/detail pages.
+const DefaultCardsPerPage = 3
+
+// Server is an httptest.Server answering reportDetailByPeriod and
+// nm-report/detail. The recorded rows cover 2025-05-05 .. 2025-05-25.
+type Server struct {
+	*httptest.Server
+
+	Reports      []models.ReportDetails
+	Cards        []json.RawMessage
+	CardsPerPage int
+}
+
+func NewServer() *Server {
+	s := &Server{CardsPerPage: DefaultCardsPerPage}
+	mustLoad("fixtures/report_detail_by_period.json", &s.Reports)
+	mustLoad("fixtures/nm_report_detail_cards.json", &s.Cards)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v5/supplier/reportDetailByPeriod", s.reportDetailByPeriod)
+	mux.HandleFunc("POST /api/v2/nm-report/detail", s.nmReportDetail)
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"TS": time.Now().Format(time.RFC3339), "Status": "OK"})
+	})
+	s.Server = httptest.NewServer(authorized(mux))
+	return s
+}
+
+// Config points a wb.Client at the fake server.
+func (s *Server) Config() wb.Config {
+	return wb.Config{StatisticsURL: s.URL, AnalyticsURL: s.URL}
+}
+
+func (s *Server) reportDetailByPeriod(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dateFrom, err := time.Parse("2006-01-02", query.Get("dateFrom"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid dateFrom")
+		return
+	}
+	dateTo, err := time.Parse("2006-01-02", query.Get("dateTo"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid dateTo")
+		return
+	}
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100000
+	}
+	rrdid, _ := strconv.ParseInt(query.Get("rrdid"), 10, 64)
+
+	page := []models.ReportDetails{}
+	for _, row := range s.Reports {
+		if row.RrdID <= rrdid {
+			continue
+		}
+		rrDt, err := time.Parse("2006-01-02", row.RrDt)
+		if err != nil || rrDt.Before(dateFrom) || rrDt.After(dateTo) {
+			continue
+		}
+		page = append(page, row)
+		if len(page) == limit {
+			break
+		}
+	}
+
+	if len(page) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) nmReportDetail(w http.ResponseWriter, r *http.Request) {
+	var payload models.AnalyticOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var response struct {
+		Data struct {
+			Page       int               `json:"page"`
+			IsNextPage bool              `json:"isNextPage"`
+			Cards      []json.RawMessage `json:"cards"`
+		} `json:"data"`
+		Error     bool   `json:"error"`
+		ErrorText string `json:"errorText"`
+	}
+	if payload.Page < 1 {
+		payload.Page = 1
+	}
+	perPage := s.CardsPerPage
+	if perPage <= 0 {
+		perPage = len(s.Cards)
+	}
+	start := min((payload.Page-1)*perPage, len(s.Cards))
+	end := min(start+perPage, len(s.Cards))
+
+	response.Data.Page = payload.Page
+	response.Data.IsNextPage = end < len(s.Cards)
+	response.Data.Cards = s.Cards[start:end]
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Token builds a WB-style API token with the given claims. It is not
+// signed; neither the fake nor the service can check WB signatures.
+func Token(id string, scopes wb.Scope, expiresAt time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","kid":"fake","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]any{
+		"id":  id,
+		"exp": expiresAt.Unix(),
+		"oid": 1,
+		"s":   uint64(scopes),
+		"t":   false,
+	})
+	return header + "." + base64.RawURLEncoding.EncodeToString(claims) + ".fake"
+}
+
+func authorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if len(auth) <= len("Bearer ") {
+			writeError(w, http.StatusUnauthorized, "empty Authorization header")
+			return
+		}
+		if info, err := wb.ParseToken(strings.TrimPrefix(auth, "Bearer ")); err == nil && info.ID == RevokedTokenID {
+			writeError(w, http.StatusUnauthorized, "token is invalid")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+var requestIDs atomic.Int64
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]any{
+		"title":     http.StatusText(status),
+		"detail":    detail,
+		"status":    status,
+		"origin":    "wbfake",
+		"requestId": fmt.Sprintf("wbfake-%d", requestIDs.Add(1)),
+	})
+}
+
+func mustLoad(name string, v any) {
+	data, err := fixtures.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		panic(err)
+	}
+}